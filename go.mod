@@ -10,9 +10,11 @@ require (
 	github.com/fatih/color v1.15.0
 	github.com/flant/libjq-go v1.6.2
 	github.com/mattn/go-colorable v0.1.13
+	github.com/mattn/go-zglob v0.0.4
 	github.com/mikefarah/yq/v4 v4.34.2
 	github.com/nwidger/jsoncolor v0.3.1
 	github.com/thanhpk/randstr v1.0.4
+	github.com/yuin/goldmark v1.5.4
 	gopkg.in/op/go-logging.v1 v1.0.0-20160211212156-b2cb9fa56473
 	gopkg.in/yaml.v2 v2.4.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -76,7 +78,6 @@ require (
 	github.com/maratori/testpackage v1.0.1 // indirect
 	github.com/matoous/godox v0.0.0-20190911065817-5d6d842e92eb // indirect
 	github.com/mattn/go-isatty v0.0.17 // indirect
-	github.com/mattn/go-zglob v0.0.4 // indirect
 	github.com/mgutz/ansi v0.0.0-20170206155736-9520e82c474b // indirect
 	github.com/mitchellh/go-homedir v1.1.0 // indirect
 	github.com/mitchellh/mapstructure v1.1.2 // indirect
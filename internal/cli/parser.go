@@ -28,6 +28,9 @@ Options:
 Commands:
 	instances  		Manage Confluence instance configuration
 	upload  		Upload resources to Confluence
+	diff  			Show the differences between local resources and Confluence
+	drift  			List pages changed in Confluence since y2c published them
+	pull  			Import the pages of a Confluence space into a space directory
 	render  		Render a resource to a specific output format
 	hooks 			List or show the configured hooks
 	anchor 			Anchor a space to a parent page 		
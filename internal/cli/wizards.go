@@ -22,10 +22,29 @@ func NewInstanceWizard(baseDir string) (confluence.InstanceConfig, string) {
 	hostPrompt := &survey.Input{Message: "Confluence Host"}
 	survey.AskOne(hostPrompt, &instance.Host, survey.WithValidator(survey.Required))
 
+	authTypes := []string{confluence.AUTH_BASIC, confluence.AUTH_OAUTH}
+	if instance.Type == "server" {
+		authTypes = []string{confluence.AUTH_BASIC, confluence.AUTH_BEARER, confluence.AUTH_OAUTH}
+	}
+	authTypeSelect := &survey.Select{
+		Message: "Authentication",
+		Options: authTypes,
+		Default: confluence.AUTH_BASIC,
+	}
+	survey.AskOne(authTypeSelect, &instance.Auth_type, survey.WithValidator(survey.Required))
+
 	var err error
-	if instance.Type == "cloud" {
+	switch {
+	case instance.Auth_type == confluence.AUTH_BEARER:
+		err = survey.Ask(getBearerQuestions(instance.Host), &instance)
+	case instance.Auth_type == confluence.AUTH_OAUTH:
+		err = survey.Ask(getOAuthInstanceQuestions(instance.Host, instance.Type), &instance)
+		if err == nil {
+			err = survey.Ask(getOAuthQuestions(instance.Host, instance.Type), &instance.OAuth)
+		}
+	case instance.Type == "cloud":
 		err = survey.Ask(getCloudQuestions(instance.Host), &instance)
-	} else {
+	default:
 		err = survey.Ask(getServerQuestions(instance.Host), &instance)
 	}
 	if err != nil {
@@ -33,6 +52,11 @@ func NewInstanceWizard(baseDir string) (confluence.InstanceConfig, string) {
 		os.Exit(1)
 	}
 
+	// basic is the default, keep it out of the generated config
+	if instance.Auth_type == confluence.AUTH_BASIC {
+		instance.Auth_type = ""
+	}
+
 	var instanceDir string
 	savePrompt := &survey.Select{
 		Message: "Choose where to save instance configuration:",
@@ -83,6 +107,70 @@ func getServerQuestions(host string) []*survey.Question {
 	}
 }
 
+var pat survey.Question = survey.Question{
+	Name: "token",
+	Prompt: &survey.Password{
+		Message: "Personal Access Token",
+	},
+	Validate: survey.Required,
+}
+
+func getBearerQuestions(host string) []*survey.Question {
+	return []*survey.Question{
+		getNameQuestion(host),
+		getApiPrefixQuestion("/rest/api"),
+		&pat,
+	}
+}
+
+func getOAuthInstanceQuestions(host string, confluenceType string) []*survey.Question {
+	prefix := "/rest/api"
+	if confluenceType == "cloud" {
+		prefix = "/wiki/rest/api"
+	}
+
+	return []*survey.Question{
+		getNameQuestion(host),
+		getApiPrefixQuestion(prefix),
+	}
+}
+
+func getOAuthQuestions(host string, confluenceType string) []*survey.Question {
+	questions := []*survey.Question{}
+	tokenUrl := ""
+	if confluenceType == "cloud" {
+		tokenUrl = "https://auth.atlassian.com/oauth/token"
+		// OAuth requests go through the API gateway, which identifies the site by its cloud id
+		questions = append(questions, &survey.Question{
+			Name:     "cloud_id",
+			Prompt:   &survey.Input{Message: fmt.Sprintf("Cloud ID (see https://%s/_edge/tenant_info)", host)},
+			Validate: survey.Required,
+		})
+	}
+
+	return append(questions, []*survey.Question{
+		{
+			Name:     "token_url",
+			Prompt:   &survey.Input{Message: "OAuth Token URL", Default: tokenUrl},
+			Validate: survey.Required,
+		},
+		{
+			Name:     "client_id",
+			Prompt:   &survey.Input{Message: "OAuth Client ID"},
+			Validate: survey.Required,
+		},
+		{
+			Name:     "client_secret",
+			Prompt:   &survey.Password{Message: "OAuth Client Secret"},
+			Validate: survey.Required,
+		},
+		{
+			Name:   "refresh_token",
+			Prompt: &survey.Password{Message: "OAuth Refresh Token (leave empty for the client credentials grant)"},
+		},
+	}...)
+}
+
 func getNameQuestion(host string) *survey.Question {
 	return &survey.Question{
 		Name: "name",
@@ -104,3 +192,16 @@ func getApiPrefixQuestion(prefixDefault string) *survey.Question {
 		Validate: survey.Required,
 	}
 }
+
+func Confirm(message string) bool {
+	confirmed := false
+	prompt := &survey.Confirm{
+		Message: message,
+	}
+	if err := survey.AskOne(prompt, &confirmed); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	return confirmed
+}
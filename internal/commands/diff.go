@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"github.com/NorthfieldIT/yaml2confluence/internal/cli"
+	"github.com/NorthfieldIT/yaml2confluence/internal/services"
+	"github.com/docopt/docopt-go"
+)
+
+type DiffCmd struct {
+	service services.IDiffSrv
+}
+
+func (DiffCmd) Usage() string {
+	return `
+Usage:
+	y2c diff <space_directory_or_file>
+
+Options:
+	<space_directory_or_file>  	A space directory, or a single YAML resource, to compare with Confluence
+`
+}
+
+func (dc DiffCmd) Handler(args docopt.Opts) {
+	dc.service.Diff(ToString(args["<space_directory_or_file>"]))
+}
+
+func init() {
+	cli.RegisterCommand("diff", DiffCmd{services.NewDiffService()})
+}
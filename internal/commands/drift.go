@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"github.com/NorthfieldIT/yaml2confluence/internal/cli"
+	"github.com/NorthfieldIT/yaml2confluence/internal/services"
+	"github.com/docopt/docopt-go"
+)
+
+type DriftCmd struct {
+	service services.IDriftSrv
+}
+
+func (DriftCmd) Usage() string {
+	return `
+Usage:
+	y2c drift <space_directory> [--json]
+
+Options:
+	<space_directory>  	A space directory to check for pages changed in Confluence since y2c published them
+	--json  			Print the drifted pages as JSON
+`
+}
+
+func (dc DriftCmd) Handler(args docopt.Opts) {
+	dc.service.Drift(ToString(args["<space_directory>"]), args["--json"].(bool))
+}
+
+func init() {
+	cli.RegisterCommand("drift", DriftCmd{services.NewDriftService()})
+}
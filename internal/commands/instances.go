@@ -15,24 +15,30 @@ func (ic InstanceCmd) Usage() string {
 	return `
 Usage:
 	y2c instances new [<base_dir>] [--config-only]
-	y2c instances list
+	y2c instances list [<base_dir>]
 	y2c instances decrypt <config>
+	y2c instances rotate-secret [<base_dir>]
 
 Options:
 	--config-only  	Skips directory creation, outputting the generated config file to the terminal
 	
 Sub-Commands:
 	new  			A CLI wizard to configure a new Confluence instance
-	list  			Lists all Confluence instances
+	list  			Lists the Confluence instances created with 'new' and those found in <base_dir>
 	decrypt  		Displays a config yaml file with decrypted secrets
+	rotate-secret  	Generates a new secret and re-encrypts the configs of all instances listed by 'list'
 `
 }
 
 func (ic InstanceCmd) Handler(args docopt.Opts) {
 	if args["list"].(bool) {
-		ic.service.List()
+		ic.service.List(resolveOptionalDir(args["<base_dir>"]))
 	} else if args["new"].(bool) {
 		ic.service.New(utils.ResolveAbsolutePathDir(ToString(args["<base_dir>"])), args["--config-only"].(bool))
+	} else if args["decrypt"].(bool) {
+		ic.service.Decrypt(utils.ResolveAbsolutePathFile(args["<config>"].(string)))
+	} else if args["rotate-secret"].(bool) {
+		ic.service.RotateSecret(resolveOptionalDir(args["<base_dir>"]))
 	}
 }
 
@@ -45,6 +51,15 @@ func ToString(arg interface{}) string {
 	return arg.(string)
 }
 
+// resolveOptionalDir keeps a missing directory argument empty instead of defaulting to the working directory
+func resolveOptionalDir(arg interface{}) string {
+	if arg == nil {
+		return ""
+	}
+
+	return utils.ResolveAbsolutePathDir(arg.(string))
+}
+
 func init() {
 	cli.RegisterCommand("instances", InstanceCmd{services.NewInstancesService()})
 }
@@ -16,8 +16,16 @@ func (mi *MockInstances) New(baseDir string, configOnly bool) {
 	mi.Calls = append(mi.Calls, []interface{}{"New", baseDir, configOnly})
 }
 
-func (mi *MockInstances) List() {
-	mi.Calls = append(mi.Calls, []interface{}{"List"})
+func (mi *MockInstances) List(baseDir string) {
+	mi.Calls = append(mi.Calls, []interface{}{"List", baseDir})
+}
+
+func (mi *MockInstances) Decrypt(config string) {
+	mi.Calls = append(mi.Calls, []interface{}{"Decrypt", config})
+}
+
+func (mi *MockInstances) RotateSecret(baseDir string) {
+	mi.Calls = append(mi.Calls, []interface{}{"RotateSecret", baseDir})
 }
 
 func TestInstancesHandler(t *testing.T) {
@@ -39,3 +47,21 @@ func TestInstancesHandler(t *testing.T) {
 		t.Fatalf(`Wrong call signature, expected %s, got %s`, expectedCall, actualCall)
 	}
 }
+
+func TestInstancesListHandler(t *testing.T) {
+	mockInstancesService := &MockInstances{}
+	cli.RegisterCommand("instances", InstanceCmd{mockInstancesService})
+
+	os.Args = append(os.Args[0:1], "instances", "list")
+	cli.Parse()
+
+	if len(mockInstancesService.Calls) != 1 {
+		t.Fatalf(`Expected exactly 1 call to instances service, got %d`, len(mockInstancesService.Calls))
+	}
+	expectedCall := "[List ]"
+	actualCall := fmt.Sprint(mockInstancesService.Calls[0])
+
+	if actualCall != expectedCall {
+		t.Fatalf(`Wrong call signature, expected %s, got %s`, expectedCall, actualCall)
+	}
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"github.com/NorthfieldIT/yaml2confluence/internal/cli"
+	"github.com/NorthfieldIT/yaml2confluence/internal/services"
+	"github.com/docopt/docopt-go"
+)
+
+type PullCmd struct {
+	service services.IPullSrv
+}
+
+func (PullCmd) Usage() string {
+	return `
+Usage:
+	y2c pull <space_directory> [--anchor <page_id>] [--attachments] [--overwrite] [--adopt]
+
+Options:
+	<space_directory>  		The space directory to write the pages to, it is created when missing
+	--anchor <page_id>  	Pull the pages below this page instead of the space homepage and anchor the space to it
+	--attachments  			Download the attachments of the pages
+	--overwrite  			Replace resources that already exist
+	--adopt  				Label the pulled pages as managed by y2c, so uploads update them
+`
+}
+
+func (pc PullCmd) Handler(args docopt.Opts) {
+	pc.service.Pull(ToString(args["<space_directory>"]), services.PullOptions{
+		Anchor:      ToString(args["--anchor"]),
+		Attachments: args["--attachments"].(bool),
+		Overwrite:   args["--overwrite"].(bool),
+		Adopt:       args["--adopt"].(bool),
+	})
+}
+
+func init() {
+	cli.RegisterCommand("pull", PullCmd{services.NewPullService()})
+}
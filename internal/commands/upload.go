@@ -1,8 +1,13 @@
 package commands
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+
 	"github.com/NorthfieldIT/yaml2confluence/internal/cli"
 	"github.com/NorthfieldIT/yaml2confluence/internal/services"
+	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
 	"github.com/docopt/docopt-go"
 )
 
@@ -13,19 +18,99 @@ type UploadCmd struct {
 func (ic UploadCmd) Usage() string {
 	return `
 Usage:
-	y2c upload <space_directory>
-	y2c upload -f <file> | --file <file>
-
+	y2c upload <space_directory> --dry-run [--json] [--force] [--overwrite | --skip-drifted | --pull]
+	y2c upload <space_directory> [--report <format> <report_file>] [--fail-fast | --keep-going] [--force] [--overwrite | --skip-drifted | --pull]
+	y2c upload (-f <file> | --file <file>) --dry-run [--json] [--overwrite | --skip-drifted | --pull]
+	y2c upload (-f <file> | --file <file>) [--report <format> <report_file>] [--fail-fast | --keep-going] [--overwrite | --skip-drifted | --pull]
+	y2c upload --all <instance_directory> [--spaces <pattern>] [--dry-run [--json] | [--parallel <n>] [--report <format> <report_file>] [--fail-fast | --keep-going]] [--force] [--overwrite | --skip-drifted | --pull]
 Options:
 	-f <file>, --file <file>     	The YAML resource to upload
+	--dry-run  				Print the planned changes without modifying Confluence
+	--json  				Print the plan as JSON (requires --dry-run)
+	--report <format>  		Write an upload report (json,junit) to <report_file>, also when the upload aborts
+	--fail-fast  			Stop after the first wave with a failed change (default)
+	--keep-going  			Keep publishing after failures, skipping only the descendants of pages that could not be created
+	--force  				Also delete pages that were edited in Confluence or have child pages not managed by y2c
+	--overwrite  			Publish pages that were changed in Confluence since y2c published them, replacing those changes
+	--skip-drifted  		Leave pages that were changed in Confluence since y2c published them untouched
+	--pull  				Write the Confluence body of drifted wiki and storage pages to their resources before publishing
+	--all  					Upload every space of the instance directory, also done when <space_directory> is an instance directory
+	--spaces <pattern>  	Only upload the spaces whose key matches the pattern (e.g. 'DOC*')
+	--parallel <n>  		The number of changes applied at the same time over all spaces [default: 10]
 `
 }
 
 func (ic UploadCmd) Handler(args docopt.Opts) {
-	if spaceDir := ToString(args["<space_directory>"]); spaceDir != "" {
-		ic.service.UploadSpace(spaceDir)
+	if args["--all"].(bool) {
+		ic.service.UploadInstance(ToString(args["<instance_directory>"]), getUploadOptions(args))
+	} else if spaceDir := ToString(args["<space_directory>"]); spaceDir != "" && utils.IsInstanceDirectory(spaceDir) {
+		ic.service.UploadInstance(spaceDir, getUploadOptions(args))
+	} else if spaceDir != "" {
+		ic.service.UploadSpace(spaceDir, getUploadOptions(args))
 	} else if file := ToString(args["--file"]); file != "" {
-		ic.service.UploadSingleResource(args["--file"].(string))
+		ic.service.UploadSingleResource(file, getUploadOptions(args))
+	}
+}
+
+func getUploadOptions(args docopt.Opts) services.UploadOptions {
+	return services.UploadOptions{
+		DryRun:       args["--dry-run"].(bool),
+		Json:         args["--json"].(bool),
+		ReportFormat: getReportFormat(args),
+		ReportFile:   ToString(args["<report_file>"]),
+		KeepGoing:    args["--keep-going"].(bool),
+		Force:        args["--force"].(bool),
+		Drift:        getDriftPolicy(args),
+		Spaces:       ToString(args["--spaces"]),
+		Parallel:     getParallel(args),
+	}
+}
+
+// getReportFormat checks the report format before anything is published, an unknown format would only fail once
+// the upload is done
+func getReportFormat(args docopt.Opts) string {
+	format := ToString(args["--report"])
+	if format == "" {
+		return ""
+	}
+
+	for _, f := range services.REPORT_FORMATS {
+		if f == format {
+			return format
+		}
+	}
+
+	fmt.Printf("Invalid --report format '%s', expected one of %v\n", format, services.REPORT_FORMATS)
+	os.Exit(1)
+
+	return ""
+}
+
+func getParallel(args docopt.Opts) int {
+	value := ToString(args["--parallel"])
+	if value == "" {
+		return 0
+	}
+
+	parallel, err := strconv.Atoi(value)
+	if err != nil || parallel < 1 {
+		fmt.Printf("Invalid --parallel value '%s', expected a positive number\n", value)
+		os.Exit(1)
+	}
+
+	return parallel
+}
+
+func getDriftPolicy(args docopt.Opts) string {
+	switch {
+	case args["--overwrite"].(bool):
+		return services.DRIFT_OVERWRITE
+	case args["--skip-drifted"].(bool):
+		return services.DRIFT_SKIP
+	case args["--pull"].(bool):
+		return services.DRIFT_PULL
+	default:
+		return ""
 	}
 }
 
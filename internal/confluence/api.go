@@ -6,10 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
@@ -22,34 +26,83 @@ var expectedStatusCode = map[string]int{
 	"DELETE": 204,
 }
 
+const (
+	API_V1 = "v1"
+	API_V2 = "v2"
+)
+
+var API_VERSIONS = []string{API_V1, API_V2}
+
+// ADF_REPRESENTATION is the body representation of Atlassian Document Format, it is only supported on cloud
+const ADF_REPRESENTATION = "atlas_doc_format"
+
+// ConfluenceApi is implemented by the v1 REST API (ConfluenceApiService) and the cloud v2 REST API (ConfluenceApiV2Service)
+type ConfluenceApi interface {
+	IsCloudInstance() bool
+	IsServerInstance() bool
+	GetRetryCount() int64
+	GetSpace() (bool, string, error)
+	CreateSpaceIfNotExists(space SpaceContext) (bool, string, error)
+	GetSpaceSettings() (SpaceSettings, error)
+	UpdateSpace(space SpaceContext) error
+	AddSpacePermission(permission SpacePermission) error
+	RemoveSpacePermission(permission SpacePermission) error
+	UpsertPage(page UpsertPageContext) (string, string, error)
+	DeletePage(id string) error
+	MovePage(id string, position string, targetId string) error
+	UpsertProperty(property UpsertPropertyContext) error
+	SetLabels(contentId string, labels []string) error
+	UpsertAttachment(contentId string, file string) (string, error)
+	DeleteAttachment(id string) error
+	GetManagedContent() ([]ConfluencePageExpanded, string, error)
+	GetManagedPage(id string) (ConfluencePageExpanded, string, error)
+	GetDescendants(ids []string) ([]ConfluencePageExpanded, error)
+	GetPagesWithBody(ancestorId string) ([]ConfluencePageExpanded, error)
+	GetAttachments(contentId string) ([]ConfluenceAttachment, string, error)
+	DownloadAttachment(url string) ([]byte, error)
+	GetCurrentUser() (string, error)
+	GetPageBody(id string) (string, error)
+	ConvertToStorage(value string, representation string) (string, error)
+}
+
+// NewConfluenceApi returns the API backend selected by the api_version of the config
+func NewConfluenceApi(spaceKey string, config InstanceConfig) ConfluenceApi {
+	api := NewConfluenceApiService(spaceKey, config)
+	if config.GetApiVersion() == API_V2 {
+		return NewConfluenceApiV2Service(api)
+	}
+
+	return api
+}
+
 type ConfluenceApiService struct {
 	config   InstanceConfig
 	spaceKey string
 	client   http.Client
-	authKey  string
+	auth     authenticator
 	isCloud  bool
+	retries  *int64
 }
 type NoOpResponse struct{}
 type ConfluenceResponse interface {
-	ConfluenceContentResponse | ConfluenceSearchResultsResponse | ConfluenceSpaceResponse | NoOpResponse
+	ConfluenceContentResponse | ConfluenceSearchResultsResponse | ConfluenceSpaceResponse | ConfluenceContentBodyResponse |
+		ConfluencePageV2Response | ConfluenceSpacesV2Response | ConfluenceAttachmentsResponse | ConfluenceUser | NoOpResponse
 }
 
 func NewConfluenceApiService(spaceKey string, config InstanceConfig) ConfluenceApiService {
-	authKey := config.API_token
-	isCloud := true
-	if config.Type == "server" {
-		authKey = config.Password
-		isCloud = false
+	client, err := newHttpClient(config.HTTP)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
 	}
 
 	return ConfluenceApiService{
 		config:   config,
 		spaceKey: spaceKey,
-		client: http.Client{
-			Timeout: time.Second * 10,
-		},
-		authKey: authKey,
-		isCloud: isCloud,
+		client:   client,
+		auth:     newAuthenticator(config, client),
+		isCloud:  config.Type != "server",
+		retries:  new(int64),
 	}
 }
 func (api ConfluenceApiService) IsCloudInstance() bool {
@@ -58,15 +111,71 @@ func (api ConfluenceApiService) IsCloudInstance() bool {
 func (api ConfluenceApiService) IsServerInstance() bool {
 	return !api.isCloud
 }
+
+// GetRetryCount returns how many requests have been retried so far
+func (api ConfluenceApiService) GetRetryCount() int64 {
+	return atomic.LoadInt64(api.retries)
+}
 func (api ConfluenceApiService) request(method string, URI string, body []byte) (*http.Response, error) {
-	URL := api.config.Protocol + "://" + api.config.Host + filepath.Join(api.config.API_prefix, URI)
+	return api.requestWithPrefix(method, api.config.API_prefix, URI, body)
+}
+
+func (api ConfluenceApiService) requestWithPrefix(method string, prefix string, URI string, body []byte) (*http.Response, error) {
+	return api.requestWithHeaders(method, prefix, URI, body, nil)
+}
+
+// requestWithHeaders sends a request with headers that replace the defaults, e.g. the JSON Content-Type
+func (api ConfluenceApiService) requestWithHeaders(method string, prefix string, URI string, body []byte, headers map[string]string) (*http.Response, error) {
+	URL := api.config.GetApiUrl() + filepath.Join(prefix, URI)
+	retry := api.config.Retry
+	reauthenticated := false
+
+	for attempt := 0; ; attempt++ {
+		resp, err := api.send(method, URL, body, headers)
+
+		// expired or revoked OAuth tokens are renewed once per request
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized && !reauthenticated && api.auth.invalidate() {
+			reauthenticated = true
+			resp.Body.Close()
+			continue
+		}
+
+		if attempt >= retry.MaxRetries || !shouldRetry(method, resp, err) {
+			return resp, err
+		}
+
+		delay := retry.getRetryDelay(attempt, resp)
+		reason := ""
+		if resp != nil {
+			reason = resp.Status
+		} else {
+			reason = err.Error()
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		atomic.AddInt64(api.retries, 1)
+		fmt.Printf("Retrying %s %s in %s (%s, retry %d of %d)\n", method, URI, delay.Round(time.Millisecond), reason, attempt+1, retry.MaxRetries)
+		time.Sleep(delay)
+	}
+}
+
+func (api ConfluenceApiService) send(method string, URL string, body []byte, headers map[string]string) (*http.Response, error) {
 	req, err := http.NewRequest(method, URL, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
+	for name, value := range api.config.HTTP.Headers {
+		req.Header.Set(name, value)
+	}
 	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
 
-	req.SetBasicAuth(api.config.User, api.authKey)
+	if err := api.auth.authenticate(req); err != nil {
+		return nil, err
+	}
 
 	resp, err := api.client.Do(req)
 	if err != nil {
@@ -102,21 +211,62 @@ func unmarshallResponse[T ConfluenceResponse](resp *http.Response, err error) (T
 	return result, nil
 }
 
-func (api ConfluenceApiService) CreateSpaceIfNotExists() (bool, string, error) {
-	// check is space exists already, if so, return
-	content, err := unmarshallResponse[ConfluenceSpaceResponse](api.request("GET", fmt.Sprintf("/space/%s?expand=homepage", api.spaceKey), nil))
-	if err == nil {
-		return true, content.Homepage.Id, nil
+func (api ConfluenceApiService) GetSpace() (bool, string, error) {
+	resp, err := api.request("GET", fmt.Sprintf("/space/%s?expand=homepage", api.spaceKey), nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, "", nil
 	}
 
-	payload := ConfluenceSpacePayload{
-		Key:  api.spaceKey,
-		Name: api.spaceKey,
+	content, err := unmarshallResponse[ConfluenceSpaceResponse](resp, err)
+	if err != nil {
+		return false, "", err
 	}
 
-	postBody, _ := json.Marshal(payload)
+	return true, content.Homepage.Id, nil
+}
+
+// SpaceContext is the configuration of a space written by CreateSpaceIfNotExists and UpdateSpace
+type SpaceContext interface {
+	GetName() string
+	GetDescription() string
+	IsPrivate() bool
+}
+
+// SpaceSettings are the settings of a space that y2c reconciles
+type SpaceSettings struct {
+	Name        string
+	Description string
+	Type        string
+	Permissions []SpacePermission
+}
+
+// SpacePermission grants an operation on a target (e.g. create page) to a group or a user
+type SpacePermission struct {
+	Id string
+	// SubjectType is group or user
+	SubjectType string
+	// Subject is the group name or the account id of the user
+	Subject   string
+	Operation string
+	Target    string
+}
+
+func (api ConfluenceApiService) CreateSpaceIfNotExists(space SpaceContext) (bool, string, error) {
+	// check is space exists already, if so, return
+	exists, homepageId, err := api.GetSpace()
+	if err != nil || exists {
+		return exists, homepageId, err
+	}
 
-	content, err = unmarshallResponse[ConfluenceSpaceResponse](api.request("POST", "/space/", postBody))
+	postBody, _ := json.Marshal(newSpacePayload(api.spaceKey, space))
+
+	// only the creator of a private space can see it until permissions are granted
+	uri := "/space/"
+	if space.IsPrivate() {
+		uri = "/space/_private"
+	}
+
+	content, err := unmarshallResponse[ConfluenceSpaceResponse](api.request("POST", uri, postBody))
 	if err != nil {
 		return false, "", err
 	}
@@ -125,11 +275,100 @@ func (api ConfluenceApiService) CreateSpaceIfNotExists() (bool, string, error) {
 
 }
 
+func newSpacePayload(key string, space SpaceContext) ConfluenceSpacePayload {
+	payload := ConfluenceSpacePayload{
+		Key:  key,
+		Name: space.GetName(),
+	}
+	if payload.Name == "" {
+		payload.Name = key
+	}
+	if space.GetDescription() != "" {
+		payload.Description = &SpaceDescription{Storage{Value: space.GetDescription(), Representation: "plain"}}
+	}
+
+	return payload
+}
+
+// GetSpaceSettings returns the name, description, type and, on cloud, the permissions of the space
+func (api ConfluenceApiService) GetSpaceSettings() (SpaceSettings, error) {
+	expand := "description.plain"
+	if api.IsCloudInstance() {
+		expand += ",permissions"
+	}
+
+	content, err := unmarshallResponse[ConfluenceSpaceResponse](api.request("GET", fmt.Sprintf("/space/%s?expand=%s", api.spaceKey, expand), nil))
+	if err != nil {
+		return SpaceSettings{}, err
+	}
+
+	settings := SpaceSettings{
+		Name:        content.Name,
+		Description: content.Description.Plain.Value,
+		Type:        content.Type,
+		Permissions: []SpacePermission{},
+	}
+
+	for _, p := range content.Permissions {
+		permission := SpacePermission{Id: strconv.Itoa(p.Id), Operation: p.Operation.Operation, Target: p.Operation.TargetType}
+		for _, group := range p.Subjects.Group.Results {
+			permission.SubjectType, permission.Subject = "group", group.Name
+			settings.Permissions = append(settings.Permissions, permission)
+		}
+		for _, user := range p.Subjects.User.Results {
+			permission.SubjectType, permission.Subject = "user", user.AccountId
+			settings.Permissions = append(settings.Permissions, permission)
+		}
+	}
+
+	return settings, nil
+}
+
+// UpdateSpace writes the name and description of the space
+func (api ConfluenceApiService) UpdateSpace(space SpaceContext) error {
+	postBody, _ := json.Marshal(newSpacePayload(api.spaceKey, space))
+
+	_, err := api.request("PUT", fmt.Sprintf("/space/%s", api.spaceKey), postBody)
+
+	return err
+}
+
+// AddSpacePermission grants a permission of the space, space permissions can only be managed on cloud
+func (api ConfluenceApiService) AddSpacePermission(permission SpacePermission) error {
+	if api.IsServerInstance() {
+		return errors.New("Space permissions are only supported by Confluence Cloud")
+	}
+
+	payload := ConfluenceSpacePermissionPayload{
+		Subject:   PermissionSubject{Type: permission.SubjectType, Identifier: permission.Subject},
+		Operation: PermissionOperation{Key: permission.Operation, Target: permission.Target},
+	}
+
+	postBody, _ := json.Marshal(payload)
+
+	_, err := api.request("POST", fmt.Sprintf("/space/%s/permission", api.spaceKey), postBody)
+
+	return err
+}
+
+// RemoveSpacePermission revokes a permission returned by GetSpaceSettings
+func (api ConfluenceApiService) RemoveSpacePermission(permission SpacePermission) error {
+	if api.IsServerInstance() {
+		return errors.New("Space permissions are only supported by Confluence Cloud")
+	}
+
+	_, err := api.request("DELETE", fmt.Sprintf("/space/%s/permission/%s", api.spaceKey, permission.Id), nil)
+
+	return err
+}
+
 type UpsertPageContext interface {
 	GetId() string
 	GetTitle() string
 	GetAncestorId() string
 	GetContent() string
+	GetRepresentation() string
+	GetEditorVersion() string
 	GetLabels() []string
 	GetIncrementedVersion() int
 	IsUpdate() bool
@@ -160,10 +399,7 @@ func (api ConfluenceApiService) UpsertPage(page UpsertPageContext) (string, stri
 			Number:    page.GetIncrementedVersion(),
 			MinorEdit: true,
 		},
-		Body: Body{Storage{
-			Value:          page.GetContent(),
-			Representation: "wiki",
-		}},
+		Body: newBody(page.GetContent(), page.GetRepresentation()),
 		Metadata: Metadata{
 			Properties{Editor{
 				Value: "V1",
@@ -172,6 +408,14 @@ func (api ConfluenceApiService) UpsertPage(page UpsertPageContext) (string, stri
 		},
 	}
 
+	if page.GetRepresentation() == ADF_REPRESENTATION {
+		if api.IsServerInstance() {
+			return "", "", errors.New(fmt.Sprintf("%s\n%s is only supported by Confluence Cloud", page.GetTitle(), ADF_REPRESENTATION))
+		}
+		// ADF pages can only be edited in the cloud editor
+		payload.Metadata.Properties.Editor.Value = "v2"
+	}
+
 	if page.GetAncestorId() != "" {
 		payload.Ancestors = append(payload.Ancestors, PageId{page.GetAncestorId()})
 	}
@@ -184,8 +428,21 @@ func (api ConfluenceApiService) UpsertPage(page UpsertPageContext) (string, stri
 		return "", "", err
 	}
 
-	return content.Id, content.Links.Base + content.Links.Webui, nil
+	return content.Id, api.getLinkBase(content.Links.Base) + content.Links.Webui, nil
+
+}
+
+// newBody sends ADF documents as atlas_doc_format, all other representations are sent as storage
+func newBody(value string, representation string) Body {
+	storage := &Storage{
+		Value:          value,
+		Representation: representation,
+	}
+	if representation == ADF_REPRESENTATION {
+		return Body{AtlasDocFormat: storage}
+	}
 
+	return Body{Storage: storage}
 }
 
 func (api ConfluenceApiService) DeletePage(id string) error {
@@ -199,8 +456,16 @@ func (api ConfluenceApiService) DeletePage(id string) error {
 	return err
 }
 
+// MovePage moves a page before or after a sibling, or appends it to the children of the target with append
+func (api ConfluenceApiService) MovePage(id string, position string, targetId string) error {
+	_, err := api.request("PUT", fmt.Sprintf("/content/%s/move/%s/%s", id, position, targetId), nil)
+
+	return err
+}
+
 type UpsertPropertyContext interface {
 	GetId() string
+	GetPropertyId() string
 	GetKey() string
 	GetValue() string
 	GetIncrementedVersion() int
@@ -239,9 +504,144 @@ func (api ConfluenceApiService) SetLabels(contentId string, labels []string) err
 	return err
 }
 
+// UpsertAttachment uploads a file to the attachments of a page, replacing the attachment with the same file name.
+// It returns the id of the attachment.
+func (api ConfluenceApiService) UpsertAttachment(contentId string, file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(file))
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	writer.WriteField("minorEdit", "true")
+	writer.Close()
+
+	headers := map[string]string{
+		"Content-Type": writer.FormDataContentType(),
+		// attachment uploads are rejected without it to protect against XSRF
+		"X-Atlassian-Token": "no-check",
+	}
+
+	attachments, err := unmarshallResponse[ConfluenceAttachmentsResponse](api.requestWithHeaders("PUT", api.config.API_prefix, fmt.Sprintf("/content/%s/child/attachment", contentId), body.Bytes(), headers))
+	if err != nil {
+		return "", err
+	}
+	if len(attachments.Results) == 0 {
+		return "", errors.New(fmt.Sprintf("Uploading %s returned no attachment", filepath.Base(file)))
+	}
+
+	return attachments.Results[0].Id, nil
+}
+
+func (api ConfluenceApiService) DeleteAttachment(id string) error {
+	_, err := api.request("DELETE", fmt.Sprintf("/content/%s", id), nil)
+
+	return err
+}
+
+// the details of the pages y2c publishes
+const MANAGED_CONTENT_EXPAND = "version,ancestors,metadata.properties.sha256,metadata.properties.attachments,metadata.properties.published_version,metadata.properties.resource_id,metadata.properties.position,metadata.labels"
+
 func (api ConfluenceApiService) GetManagedContent() ([]ConfluencePageExpanded, string, error) {
-	cql := url.PathEscape(fmt.Sprintf(`label="%s" AND space.key="%s"`, constants.GENERATED_BY_LABEL, api.spaceKey))
-	URI := fmt.Sprintf("/content/search?cql=%s&expand=version,ancestors,metadata.properties.sha256,metadata.labels&limit=80", cql)
+	cql := fmt.Sprintf(`label="%s" AND space.key="%s"`, constants.GENERATED_BY_LABEL, api.spaceKey)
+
+	return api.search(cql, MANAGED_CONTENT_EXPAND)
+}
+
+// GetManagedPage returns a single page with the details of GetManagedContent, whether y2c manages it or not
+func (api ConfluenceApiService) GetManagedPage(id string) (ConfluencePageExpanded, string, error) {
+	pages, base, err := api.search(fmt.Sprintf("id=%s", id), MANAGED_CONTENT_EXPAND)
+	if err != nil {
+		return ConfluencePageExpanded{}, "", err
+	}
+	if len(pages) == 0 {
+		return ConfluencePageExpanded{}, "", errors.New(fmt.Sprintf("Page %s does not exist", id))
+	}
+
+	return pages[0], base, nil
+}
+
+// GetDescendants returns all pages below the pages with the given ids
+func (api ConfluenceApiService) GetDescendants(ids []string) ([]ConfluencePageExpanded, error) {
+	pages := []ConfluencePageExpanded{}
+
+	// keep the CQL short, ids are queried in batches
+	for start := 0; start < len(ids); start += 50 {
+		end := start + 50
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		cql := fmt.Sprintf(`type=page AND ancestor IN (%s)`, strings.Join(ids[start:end], ","))
+		results, _, err := api.search(cql, "version,ancestors,metadata.labels")
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, results...)
+	}
+
+	return pages, nil
+}
+
+// GetPagesWithBody returns all pages below the page with the id, with their body in storage representation and
+// the properties of managed pages
+func (api ConfluenceApiService) GetPagesWithBody(ancestorId string) ([]ConfluencePageExpanded, error) {
+	pages, _, err := api.search(fmt.Sprintf(`type=page AND ancestor=%s`, ancestorId), MANAGED_CONTENT_EXPAND+",body.storage")
+
+	return pages, err
+}
+
+// GetAttachments returns the attachments of a page, together with the base URL of their download links
+func (api ConfluenceApiService) GetAttachments(contentId string) ([]ConfluenceAttachment, string, error) {
+	attachments, err := unmarshallResponse[ConfluenceAttachmentsResponse](api.request("GET", fmt.Sprintf("/content/%s/child/attachment?limit=200", contentId), nil))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// download links are relative to the site, OAuth tokens are only accepted by the API gateway
+	base := attachments.Links.Base
+	if api.config.UsesApiGateway() {
+		base = api.config.GetApiUrl() + api.config.getContextPath()
+	}
+
+	return attachments.Results, base, nil
+}
+
+// DownloadAttachment downloads the file of an attachment from the absolute URL of its download link
+func (api ConfluenceApiService) DownloadAttachment(url string) ([]byte, error) {
+	resp, err := api.send("GET", url, nil, nil)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// GetCurrentUser returns the account id (cloud) or user name (server) of the authenticated user
+func (api ConfluenceApiService) GetCurrentUser() (string, error) {
+	user, err := unmarshallResponse[ConfluenceUser](api.request("GET", "/user/current", nil))
+	if err != nil {
+		return "", err
+	}
+	if user.AccountId != "" {
+		return user.AccountId, nil
+	}
+
+	return user.Username, nil
+}
+
+// search returns all pages matching the CQL query, following the pagination links
+func (api ConfluenceApiService) search(cql string, expand string) ([]ConfluencePageExpanded, string, error) {
+	URI := fmt.Sprintf("/content/search?cql=%s&expand=%s&limit=80", url.PathEscape(cql), expand)
 
 	sr, err := unmarshallResponse[ConfluenceSearchResultsResponse](api.request("GET", URI, nil))
 	if err != nil {
@@ -261,5 +661,42 @@ func (api ConfluenceApiService) GetManagedContent() ([]ConfluencePageExpanded, s
 		pages = append(pages, sr.Results...)
 	}
 
-	return pages, sr.Links.Base, nil
+	return pages, api.getLinkBase(sr.Links.Base), nil
+}
+
+// getLinkBase returns the base URL of page links, the site URL derived from the config is used when a response
+// has no base link or comes from the API gateway
+func (api ConfluenceApiService) getLinkBase(base string) string {
+	if base != "" && !api.config.UsesApiGateway() {
+		return base
+	}
+
+	return api.config.GetSiteUrl()
+}
+
+// GetPageBody returns the current body of a page in storage representation
+func (api ConfluenceApiService) GetPageBody(id string) (string, error) {
+	content, err := unmarshallResponse[ConfluenceContentResponse](api.request("GET", fmt.Sprintf("/content/%s?expand=body.storage", id), nil))
+	if err != nil {
+		return "", err
+	}
+
+	return content.Body.Storage.Value, nil
+}
+
+// ConvertToStorage converts a body from the given representation (e.g. wiki) to storage representation
+func (api ConfluenceApiService) ConvertToStorage(value string, representation string) (string, error) {
+	payload := Storage{
+		Value:          value,
+		Representation: representation,
+	}
+
+	postBody, _ := json.Marshal(payload)
+
+	content, err := unmarshallResponse[ConfluenceContentBodyResponse](api.request("POST", "/contentbody/convert/storage", postBody))
+	if err != nil {
+		return "", err
+	}
+
+	return content.Value, nil
 }
@@ -0,0 +1,194 @@
+package confluence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
+)
+
+// ConfluenceApiV2Service publishes pages and their properties with the cloud v2 REST API.
+// CQL search, labels, body conversion and space creation have no v2 equivalent, they use the v1 API.
+type ConfluenceApiV2Service struct {
+	ConfluenceApiService
+	prefix string
+	// the v2 API references spaces by id, it is resolved by GetSpace
+	spaceId *cachedSpaceId
+}
+
+// cachedSpaceId is shared by the copies of the service, pages may be uploaded in parallel
+type cachedSpaceId struct {
+	mu sync.Mutex
+	id string
+}
+
+func (c *cachedSpaceId) get() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.id
+}
+
+func (c *cachedSpaceId) set(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.id = id
+}
+
+func NewConfluenceApiV2Service(api ConfluenceApiService) ConfluenceApiV2Service {
+	return ConfluenceApiV2Service{
+		ConfluenceApiService: api,
+		prefix:               api.config.GetApiV2Prefix(),
+		spaceId:              &cachedSpaceId{},
+	}
+}
+
+func (api ConfluenceApiV2Service) request(method string, URI string, body []byte) (*http.Response, error) {
+	return api.requestWithPrefix(method, api.prefix, URI, body)
+}
+
+func (api ConfluenceApiV2Service) GetSpace() (bool, string, error) {
+	spaces, err := unmarshallResponse[ConfluenceSpacesV2Response](api.request("GET", "/spaces?keys="+url.QueryEscape(api.spaceKey), nil))
+	if err != nil {
+		return false, "", err
+	}
+	if len(spaces.Results) == 0 {
+		return false, "", nil
+	}
+
+	api.spaceId.set(spaces.Results[0].Id)
+
+	return true, spaces.Results[0].HomepageId, nil
+}
+
+func (api ConfluenceApiV2Service) CreateSpaceIfNotExists(space SpaceContext) (bool, string, error) {
+	exists, homepageId, err := api.GetSpace()
+	if err != nil || exists {
+		return exists, homepageId, err
+	}
+
+	if _, _, err := api.ConfluenceApiService.CreateSpaceIfNotExists(space); err != nil {
+		return false, "", err
+	}
+
+	// resolve the id of the new space
+	_, homepageId, err = api.GetSpace()
+
+	return false, homepageId, err
+}
+
+func (api ConfluenceApiV2Service) getSpaceId() (string, error) {
+	if spaceId := api.spaceId.get(); spaceId != "" {
+		return spaceId, nil
+	}
+
+	exists, _, err := api.GetSpace()
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", errors.New(fmt.Sprintf("Space %s does not exist", api.spaceKey))
+	}
+
+	return api.spaceId.get(), nil
+}
+
+func (api ConfluenceApiV2Service) UpsertPage(page UpsertPageContext) (string, string, error) {
+	spaceId, err := api.getSpaceId()
+	if err != nil {
+		return "", "", err
+	}
+
+	method := "POST"
+	uri := "/pages"
+
+	payload := ConfluencePageV2Payload{
+		SpaceId:  spaceId,
+		Status:   "current",
+		Title:    page.GetTitle(),
+		ParentId: page.GetAncestorId(),
+		Body: Storage{
+			Value:          page.GetContent(),
+			Representation: page.GetRepresentation(),
+		},
+	}
+
+	if page.IsUpdate() {
+		method = "PUT"
+		uri = uri + "/" + page.GetId()
+		payload.Id = page.GetId()
+		payload.Version = &VersionV2{Number: page.GetIncrementedVersion()}
+	}
+
+	postBody, _ := json.Marshal(payload)
+
+	content, err := unmarshallResponse[ConfluencePageV2Response](api.request(method, uri, postBody))
+	if err != nil {
+		fmt.Println(page.GetTitle())
+		return "", "", err
+	}
+
+	// the v2 API does not write labels, they are required to find managed content
+	labels := append([]string{constants.GENERATED_BY_LABEL}, page.GetLabels()...)
+	if err := api.ConfluenceApiService.SetLabels(content.Id, labels); err != nil {
+		return "", "", err
+	}
+
+	// pages are opened in the editor stored in the editor property, only new pages get it. ADF requires the cloud editor
+	if !page.IsUpdate() && (strings.EqualFold(page.GetEditorVersion(), "v2") || page.GetRepresentation() == ADF_REPRESENTATION) {
+		payload := ConfluencePropertyV2Payload{Key: "editor", Value: "v2"}
+		postBody, _ := json.Marshal(payload)
+		if _, err := api.request("POST", fmt.Sprintf("/pages/%s/properties", content.Id), postBody); err != nil {
+			return "", "", err
+		}
+	}
+
+	return content.Id, api.getLinkBase(content.Links.Base) + content.Links.Webui, nil
+}
+
+func (api ConfluenceApiV2Service) DeletePage(id string) error {
+	_, err := api.request("DELETE", fmt.Sprintf("/pages/%s", id), nil)
+
+	// permanently delete the trashed page, so it can't collide with the title of moved pages
+	api.request("DELETE", fmt.Sprintf("/pages/%s?purge=true", id), nil)
+
+	return err
+}
+
+func (api ConfluenceApiV2Service) UpsertProperty(property UpsertPropertyContext) error {
+	method := "POST"
+	uri := fmt.Sprintf("/pages/%s/properties", property.GetId())
+
+	payload := ConfluencePropertyV2Payload{
+		Key:   property.GetKey(),
+		Value: property.GetValue(),
+	}
+
+	if property.IsUpdate() && property.GetPropertyId() != "" {
+		method = "PUT"
+		uri = uri + "/" + property.GetPropertyId()
+		payload.Version = &VersionV2{Number: property.GetIncrementedVersion()}
+	}
+
+	postBody, _ := json.Marshal(payload)
+
+	_, err := api.request(method, uri, postBody)
+
+	return err
+}
+
+// GetPageBody returns the current body of a page in storage representation
+func (api ConfluenceApiV2Service) GetPageBody(id string) (string, error) {
+	content, err := unmarshallResponse[ConfluencePageV2Response](api.request("GET", fmt.Sprintf("/pages/%s?body-format=storage", id), nil))
+	if err != nil {
+		return "", err
+	}
+
+	return content.Body.Storage.Value, nil
+}
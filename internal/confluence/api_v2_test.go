@@ -0,0 +1,95 @@
+package confluence
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type testPage struct {
+	id      string
+	version int
+}
+
+func (p testPage) GetId() string              { return p.id }
+func (p testPage) GetTitle() string           { return "Page" }
+func (p testPage) GetAncestorId() string      { return "1" }
+func (p testPage) GetContent() string         { return "<p>content</p>" }
+func (p testPage) GetRepresentation() string  { return "storage" }
+func (p testPage) GetEditorVersion() string   { return "V2" }
+func (p testPage) GetLabels() []string        { return []string{"docs"} }
+func (p testPage) GetIncrementedVersion() int { return p.version + 1 }
+func (p testPage) IsUpdate() bool             { return p.id != "" }
+
+func TestGetApiV2Prefix(t *testing.T) {
+	ic := InstanceConfig{API_prefix: "/wiki/rest/api"}
+	if prefix := ic.GetApiV2Prefix(); prefix != "/wiki/api/v2" {
+		t.Errorf("Expected /wiki/api/v2, got %s", prefix)
+	}
+}
+
+func TestUpsertPageV2(t *testing.T) {
+	requests := []string{}
+	v1, server := newTestApiService(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v2/spaces"):
+			w.Write([]byte(`{"results":[{"id":"77","key":"DEMO","homepageId":"1"}]}`))
+		case r.URL.Path == "/api/v2/pages":
+			w.Write([]byte(`{"id":"100","_links":{"webui":"/spaces/DEMO/pages/100"}}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	})
+	defer server.Close()
+	v1.config.API_prefix = "/wiki/rest/api"
+	v1.config.API_v2_prefix = "/api/v2"
+	api := NewConfluenceApiV2Service(v1)
+
+	id, link, err := api.UpsertPage(testPage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "100" || link != "http://"+v1.config.Host+"/wiki/spaces/DEMO/pages/100" {
+		t.Errorf("Unexpected id %s and link %s", id, link)
+	}
+
+	expected := []string{
+		`GET /api/v2/spaces?keys=DEMO `,
+		`POST /api/v2/pages {"spaceId":"77","status":"current","title":"Page","parentId":"1","body":{"value":"\u003cp\u003econtent\u003c/p\u003e","representation":"storage"}}`,
+		`POST /wiki/rest/api/content/100/label [{"prefix":"global","name":"generated_by=y2c"},{"prefix":"global","name":"docs"}]`,
+		`POST /api/v2/pages/100/properties {"key":"editor","value":"v2"}`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected requests\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(requests, "\n"))
+	}
+}
+
+func TestUpsertPageV2InParallel(t *testing.T) {
+	v1, server := newTestApiService(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/v2/spaces") {
+			w.Write([]byte(`{"results":[{"id":"77","key":"DEMO","homepageId":"1"}]}`))
+			return
+		}
+		w.Write([]byte(`{"id":"100","_links":{"webui":"/spaces/DEMO/pages/100"}}`))
+	})
+	defer server.Close()
+	v1.config.API_v2_prefix = "/api/v2"
+	api := NewConfluenceApiV2Service(v1)
+
+	errs := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, _, err := api.UpsertPage(testPage{})
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package confluence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AUTH_BASIC  = "basic"
+	AUTH_BEARER = "bearer"
+	AUTH_OAUTH  = "oauth"
+)
+
+var AUTH_TYPES = []string{AUTH_BASIC, AUTH_BEARER, AUTH_OAUTH}
+
+// refresh OAuth access tokens slightly before they expire, so in-flight requests don't use a stale token
+const OAUTH_EXPIRY_MARGIN = 30 * time.Second
+
+type OAuthConfig struct {
+	// CloudId identifies the site of a Confluence Cloud instance, OAuth tokens are only accepted by the API gateway
+	CloudId      string   `yaml:"cloud_id,omitempty" survey:"cloud_id"`
+	TokenUrl     string   `yaml:"token_url" survey:"token_url"`
+	ClientId     string   `yaml:"client_id" survey:"client_id"`
+	ClientSecret string   `yaml:"client_secret" survey:"client_secret"`
+	RefreshToken string   `yaml:"refresh_token,omitempty" survey:"refresh_token"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+}
+
+type authenticator interface {
+	authenticate(req *http.Request) error
+	// invalidate discards cached credentials after a 401, returning true when new credentials can be obtained
+	invalidate() bool
+}
+
+func newAuthenticator(config InstanceConfig, client http.Client) authenticator {
+	switch config.GetAuthType() {
+	case AUTH_BEARER:
+		return bearerAuth{config.Token}
+	case AUTH_OAUTH:
+		return getOAuthAuth(config, client)
+	default:
+		password := config.API_token
+		if config.Type == "server" {
+			password = config.Password
+		}
+		return basicAuth{config.User, password}
+	}
+}
+
+type basicAuth struct {
+	user     string
+	password string
+}
+
+func (ba basicAuth) authenticate(req *http.Request) error {
+	req.SetBasicAuth(ba.user, ba.password)
+	return nil
+}
+func (basicAuth) invalidate() bool { return false }
+
+// bearerAuth is used for Personal Access Tokens of Confluence Server and Data Center
+type bearerAuth struct {
+	token string
+}
+
+func (ba bearerAuth) authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+ba.token)
+	return nil
+}
+func (bearerAuth) invalidate() bool { return false }
+
+// oauthAuth obtains access tokens with the client credentials grant, or the refresh token grant when a
+// refresh token is configured, and renews them when they expire
+type oauthAuth struct {
+	config       OAuthConfig
+	client       http.Client
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiry       time.Time
+	// saveRefreshToken keeps a rotated refresh token for the next run
+	saveRefreshToken func(token string) error
+}
+
+// the APIs of all spaces of an instance share one OAuth authenticator, a rotated refresh token revokes the previous one
+var oauthAuths = map[string]*oauthAuth{}
+var oauthAuthsMu sync.Mutex
+
+func getOAuthAuth(config InstanceConfig, client http.Client) *oauthAuth {
+	oauthAuthsMu.Lock()
+	defer oauthAuthsMu.Unlock()
+
+	key := strings.Join([]string{config.file, config.OAuth.TokenUrl, config.OAuth.ClientId}, "|")
+	if oa, ok := oauthAuths[key]; ok {
+		return oa
+	}
+
+	oa := &oauthAuth{config: config.OAuth, client: client, refreshToken: config.OAuth.RefreshToken, saveRefreshToken: config.saveRefreshToken}
+	oauthAuths[key] = oa
+
+	return oa
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (oa *oauthAuth) authenticate(req *http.Request) error {
+	token, err := oa.getAccessToken()
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (oa *oauthAuth) invalidate() bool {
+	oa.mu.Lock()
+	defer oa.mu.Unlock()
+
+	oa.accessToken = ""
+	return true
+}
+
+func (oa *oauthAuth) getAccessToken() (string, error) {
+	oa.mu.Lock()
+	defer oa.mu.Unlock()
+
+	if oa.accessToken != "" && (oa.expiry.IsZero() || time.Now().Add(OAUTH_EXPIRY_MARGIN).Before(oa.expiry)) {
+		return oa.accessToken, nil
+	}
+
+	form := url.Values{
+		"client_id":     {oa.config.ClientId},
+		"client_secret": {oa.config.ClientSecret},
+	}
+	if oa.refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", oa.refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(oa.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(oa.config.Scopes, " "))
+	}
+
+	resp, err := oa.client.PostForm(oa.config.TokenUrl, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(fmt.Sprintf("Failed to obtain OAuth access token from %s\n%s\n%s", oa.config.TokenUrl, resp.Status, string(body)))
+	}
+
+	token := oauthTokenResponse{}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", errors.New(fmt.Sprintf("No access token returned by %s", oa.config.TokenUrl))
+	}
+
+	oa.accessToken = token.AccessToken
+	oa.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		oa.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	// refresh tokens may be rotated, the previous one is revoked so the new one must be kept for the next run
+	if oa.refreshToken != "" && token.RefreshToken != "" && token.RefreshToken != oa.refreshToken {
+		// the new token is kept in memory for the rest of this run even when it can't be saved
+		oa.refreshToken = token.RefreshToken
+		if err := oa.saveRefreshToken(token.RefreshToken); err != nil {
+			fmt.Printf("Warning: the OAuth refresh token was rotated by %s but the new one could not be saved, the configured one may no longer be valid after this run\n%s\n", oa.config.TokenUrl, err.Error())
+		}
+	}
+
+	return oa.accessToken, nil
+}
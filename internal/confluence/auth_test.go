@@ -0,0 +1,95 @@
+package confluence
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
+)
+
+func TestOAuthSavesRotatedRefreshToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "old-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token": "access", "expires_in": 3600, "refresh_token": "new-token"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("Y2C_SECRET", "0123456789abcdef0123456789abcdef")
+	encrypted, _ := utils.Encrypt("old-token", "0123456789abcdef0123456789abcdef")
+
+	file := filepath.Join(t.TempDir(), "config.yml")
+	os.WriteFile(file, []byte("name: rotate\ntype: cloud\nauth_type: oauth\noauth:\n  cloud_id: abc\n  token_url: "+server.URL+"\n  client_id: id\n  client_secret: secret\n  refresh_token: "+encrypted+"\n"), 0600)
+
+	ic := LoadConfig(file)
+	oa := newAuthenticator(ic, http.Client{}).(*oauthAuth)
+	if token, err := oa.getAccessToken(); err != nil || token != "access" {
+		t.Fatalf("Expected an access token, got %s %v", token, err)
+	}
+
+	saved := ReadConfig(file).OAuth.RefreshToken
+	if !utils.IsEncrypted(saved) {
+		t.Fatalf("Expected the rotated refresh token to be saved encrypted, got %s", saved)
+	}
+	if decrypted, _ := utils.Decrypt(saved, "0123456789abcdef0123456789abcdef"); decrypted != "new-token" {
+		t.Errorf("Expected the rotated refresh token to be saved, got %s", decrypted)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the permissions of the config to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestOAuthRefreshTokenFromEnvCantBeRotated(t *testing.T) {
+	t.Setenv("Y2C_ENV_ROTATE_REFRESH_TOKEN", "old-token")
+
+	ic := InstanceConfig{Name: "env-rotate", Auth_type: AUTH_OAUTH}
+	if err := ic.saveRefreshToken("new-token"); err == nil || !strings.Contains(err.Error(), "Y2C_ENV_ROTATE_REFRESH_TOKEN") {
+		t.Errorf("Expected the rotation of a refresh token set by the environment to fail, got %v", err)
+	}
+}
+
+func TestOAuthKeepsRotatedRefreshTokenWhenItCantBeSaved(t *testing.T) {
+	refreshed := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		refreshed = append(refreshed, r.Form.Get("refresh_token"))
+		w.Write([]byte(`{"access_token": "access", "expires_in": 1, "refresh_token": "token-` + fmt.Sprint(len(refreshed)) + `"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("Y2C_ENV_KEEP_REFRESH_TOKEN", "token-0")
+	ic := InstanceConfig{Name: "env-keep", Type: "cloud", Auth_type: AUTH_OAUTH, OAuth: OAuthConfig{TokenUrl: server.URL, ClientId: "env-keep", RefreshToken: "token-0"}}
+
+	oa := newAuthenticator(ic, http.Client{}).(*oauthAuth)
+	for i := 0; i < 2; i++ {
+		if token, err := oa.getAccessToken(); err != nil || token != "access" {
+			t.Fatalf("Expected an access token although the rotated refresh token can't be saved, got %s %v", token, err)
+		}
+	}
+	if strings.Join(refreshed, ",") != "token-0,token-1" {
+		t.Errorf("Expected the rotated refresh token to be used for the next refresh, got %v", refreshed)
+	}
+}
+
+func TestApiGatewayUrl(t *testing.T) {
+	ic := InstanceConfig{Type: "cloud", Protocol: "https", Host: "example.atlassian.net", API_prefix: "/wiki/rest/api", Auth_type: AUTH_OAUTH, OAuth: OAuthConfig{CloudId: "abc"}}
+	if url := ic.GetApiUrl(); url != "https://api.atlassian.com/ex/confluence/abc" {
+		t.Errorf("Expected the API gateway URL, got %s", url)
+	}
+	if url := ic.GetSiteUrl(); url != "https://example.atlassian.net/wiki" {
+		t.Errorf("Expected the site URL, got %s", url)
+	}
+
+	ic.Auth_type = AUTH_BASIC
+	if url := ic.GetApiUrl(); url != "https://example.atlassian.net" {
+		t.Errorf("Expected the site to be used without OAuth, got %s", url)
+	}
+}
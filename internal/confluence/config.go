@@ -1,10 +1,11 @@
 package confluence
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
-	"unicode"
 
 	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
 	"gopkg.in/yaml.v3"
@@ -16,12 +17,56 @@ type InstanceConfig struct {
 	Protocol   string `yaml:"protocol,omitempty"`
 	Host       string
 	API_prefix string
-	User       string
-	API_token  string `yaml:"api_token,omitempty"`
-	Password   string `yaml:"password,omitempty"`
+	// API_version selects the REST API used for pages, v1 (default) or v2 which is only available on cloud
+	API_version string `yaml:"api_version,omitempty"`
+	// API_v2_prefix defaults to API_prefix with /rest/api replaced by /api/v2
+	API_v2_prefix string `yaml:"api_v2_prefix,omitempty"`
+	// Auth_type is one of basic (default), bearer or oauth
+	Auth_type string      `yaml:"auth_type,omitempty"`
+	User      string      `yaml:"user,omitempty"`
+	API_token string      `yaml:"api_token,omitempty"`
+	Password  string      `yaml:"password,omitempty"`
+	Token     string      `yaml:"token,omitempty"`
+	OAuth     OAuthConfig `yaml:"oauth,omitempty"`
+	// Token_command and Token_file provide the primary credential outside of the config,
+	// e.g. from a password manager or a mounted secret
+	Token_command string      `yaml:"token_command,omitempty"`
+	Token_file    string      `yaml:"token_file,omitempty"`
+	Retry         RetryConfig `yaml:"retry,omitempty"`
+	HTTP          HttpConfig  `yaml:"http,omitempty"`
+
+	// file is the config.yml the config was loaded from
+	file string
 }
 
+// OAUTH_GATEWAY_URL is the only URL accepting OAuth access tokens of Confluence Cloud, followed by the cloud id
+const OAUTH_GATEWAY_URL = "https://api.atlassian.com/ex/confluence/"
+
 func LoadConfig(file string) InstanceConfig {
+	ic := ReadConfig(file)
+
+	ic.Retry.setDefaults()
+	ic.HTTP.resolvePaths(filepath.Dir(file))
+
+	if err := ic.validateApiVersion(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	if ic.Type == "cloud" && ic.GetAuthType() == AUTH_OAUTH && ic.OAuth.CloudId == "" {
+		fmt.Printf("OAuth requires oauth.cloud_id for Confluence Cloud, it is returned by %s://%s/_edge/tenant_info\n", ic.Protocol, ic.Host)
+		os.Exit(1)
+	}
+
+	if err := resolveCredentials(&ic, filepath.Dir(file)); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	return ic
+}
+
+// ReadConfig parses a config file without resolving its credentials
+func ReadConfig(file string) InstanceConfig {
 	ic := InstanceConfig{}
 
 	data, err := os.ReadFile(file)
@@ -37,30 +82,144 @@ func LoadConfig(file string) InstanceConfig {
 	if ic.Protocol == "" {
 		ic.Protocol = "https"
 	}
+	ic.file = file
 
-	secret, err := utils.GetSecret()
-	if err != nil {
-		fmt.Println("Could not find .secret")
-		os.Exit(1)
+	return ic
+}
+
+// UsesApiGateway is true when requests are sent to the Atlassian API gateway rather than to the site
+func (ic *InstanceConfig) UsesApiGateway() bool {
+	return ic.Type == "cloud" && ic.GetAuthType() == AUTH_OAUTH && ic.OAuth.CloudId != ""
+}
+
+// GetApiUrl returns the URL the API prefixes are appended to
+func (ic *InstanceConfig) GetApiUrl() string {
+	if ic.UsesApiGateway() {
+		return OAUTH_GATEWAY_URL + ic.OAuth.CloudId
 	}
-	var authPointer *string
-	var errMsg string
-	if ic.Type == "cloud" {
-		authPointer = &ic.API_token
-		errMsg = "Could not decrypt API token"
-	} else {
-		authPointer = &ic.Password
-		errMsg = "Could not decrypt password"
+
+	return ic.Protocol + "://" + ic.Host
+}
+
+// GetSiteUrl returns the base URL of the pages of the site, e.g. https://example.atlassian.net/wiki
+func (ic *InstanceConfig) GetSiteUrl() string {
+	return ic.Protocol + "://" + ic.Host + ic.getContextPath()
+}
+
+// getContextPath returns the path of Confluence on its host, /wiki on cloud
+func (ic *InstanceConfig) getContextPath() string {
+	return strings.TrimSuffix(strings.TrimSuffix(ic.API_prefix, "/"), "/rest/api")
+}
+
+func (ic *InstanceConfig) GetApiVersion() string {
+	if ic.API_version == "" {
+		return API_V1
 	}
-	decryptedToken, err := utils.Decrypt(strings.Split(*authPointer, ":")[1], secret)
+
+	return ic.API_version
+}
+
+func (ic *InstanceConfig) GetApiV2Prefix() string {
+	if ic.API_v2_prefix != "" {
+		return ic.API_v2_prefix
+	}
+
+	return ic.getContextPath() + "/api/v2"
+}
+
+func (ic *InstanceConfig) validateApiVersion() error {
+	switch ic.GetApiVersion() {
+	case API_V1:
+		return nil
+	case API_V2:
+		if ic.Type == "server" {
+			return errors.New("api_version v2 is only available for Confluence Cloud")
+		}
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("Unknown api_version '%s', expected one of %v", ic.API_version, API_VERSIONS))
+	}
+}
+
+func (ic *InstanceConfig) GetAuthType() string {
+	if ic.Auth_type == "" {
+		return AUTH_BASIC
+	}
+
+	return ic.Auth_type
+}
+
+type SecretField struct {
+	Name string
+	// Key is the config key holding the value
+	Key string
+	// Env is the suffix of the environment variable overriding the value
+	Env      string
+	Value    *string
+	Optional bool
+}
+
+// GetSecretFields returns the config values holding secrets for the configured auth type,
+// the primary credential comes first
+func (ic *InstanceConfig) GetSecretFields() []SecretField {
+	switch ic.GetAuthType() {
+	case AUTH_BEARER:
+		return []SecretField{{Name: "token", Key: "token", Env: "TOKEN", Value: &ic.Token}}
+	case AUTH_OAUTH:
+		return []SecretField{
+			{Name: "OAuth client secret", Key: "oauth.client_secret", Env: "CLIENT_SECRET", Value: &ic.OAuth.ClientSecret},
+			{Name: "OAuth refresh token", Key: "oauth.refresh_token", Env: "REFRESH_TOKEN", Value: &ic.OAuth.RefreshToken, Optional: true},
+		}
+	default:
+		if ic.Type == "cloud" {
+			return []SecretField{{Name: "API token", Key: "api_token", Env: "TOKEN", Value: &ic.API_token}}
+		}
+		return []SecretField{{Name: "password", Key: "password", Env: "TOKEN", Value: &ic.Password}}
+	}
+}
+
+// saveRefreshToken writes a rotated OAuth refresh token to the config file, encrypted when the previous one was.
+// A refresh token set by an environment variable can't be updated.
+func (ic *InstanceConfig) saveRefreshToken(token string) error {
+	field := ic.GetSecretFields()[1]
+	cr := credentialResolver{config: ic}
+	if value, ok := os.LookupEnv(cr.getEnvName(field)); ok && value != "" {
+		return errors.New(fmt.Sprintf("%s can't be updated, set %s in config.yml to keep rotated refresh tokens", cr.getEnvName(field), field.Key))
+	}
+	if ic.file == "" {
+		return errors.New("No config file to save the refresh token to")
+	}
+
+	info, err := os.Stat(ic.file)
 	if err != nil {
-		fmt.Println(errMsg)
-		os.Exit(1)
+		return err
+	}
+	data, err := os.ReadFile(ic.file)
+	if err != nil {
+		return err
 	}
 
-	*authPointer = strings.TrimFunc(decryptedToken, func(r rune) bool {
-		return !unicode.IsGraphic(r)
-	})
+	// edit the document rather than marshalling the config, so the rest of the file is kept as written
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	node := utils.GetYamlNode(&doc, strings.Split(field.Key, "."))
+	if node == nil {
+		return errors.New(fmt.Sprintf("No %s in %s", field.Key, ic.file))
+	}
 
-	return ic
+	value := token
+	if utils.IsEncrypted(node.Value) {
+		secret, err := utils.GetSecret()
+		if err != nil {
+			return errors.New("Could not find .secret to encrypt the refresh token")
+		}
+		if value, err = utils.Encrypt(token, secret); err != nil {
+			return err
+		}
+	}
+	node.Value = value
+
+	return os.WriteFile(ic.file, utils.MarshalYamlNode(&doc), info.Mode().Perm())
 }
@@ -0,0 +1,140 @@
+package confluence
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
+)
+
+var nonEnvChars = regexp.MustCompile("[^A-Z0-9]+")
+
+// credentialResolver fills in the secret fields of a config from, in order of precedence:
+// an environment variable, token_command, token_file and the value in the config itself,
+// which is either plain text or encrypted with the y2c secret
+type credentialResolver struct {
+	config    *InstanceConfig
+	configDir string
+	secret    string
+}
+
+func resolveCredentials(ic *InstanceConfig, configDir string) error {
+	cr := credentialResolver{config: ic, configDir: configDir}
+
+	for i, field := range ic.GetSecretFields() {
+		// token_command and token_file provide the primary credential of the auth type
+		value, err := cr.resolve(field, i == 0)
+		if err != nil {
+			return err
+		}
+		if value == "" && !field.Optional {
+			return errors.New(fmt.Sprintf("No %s configured, set %s, token_command, token_file or %s in config.yml", field.Name, cr.getEnvName(field), field.Key))
+		}
+
+		*field.Value = value
+	}
+
+	return nil
+}
+
+// DecryptSecrets decrypts the encrypted values of the config in place, other sources are left untouched
+func DecryptSecrets(ic *InstanceConfig) error {
+	cr := credentialResolver{config: ic}
+
+	for _, field := range ic.GetSecretFields() {
+		if !utils.IsEncrypted(*field.Value) {
+			continue
+		}
+
+		decrypted, err := cr.decrypt(field)
+		if err != nil {
+			return err
+		}
+		*field.Value = decrypted
+	}
+
+	return nil
+}
+
+func (cr *credentialResolver) resolve(field SecretField, primary bool) (string, error) {
+	if value, ok := os.LookupEnv(cr.getEnvName(field)); ok && value != "" {
+		return value, nil
+	}
+
+	if primary && cr.config.Token_command != "" {
+		return runTokenCommand(cr.config.Token_command)
+	}
+
+	if primary && cr.config.Token_file != "" {
+		return cr.readTokenFile(cr.config.Token_file)
+	}
+
+	if utils.IsEncrypted(*field.Value) {
+		return cr.decrypt(field)
+	}
+
+	return *field.Value, nil
+}
+
+// getEnvName returns e.g. Y2C_MY_INSTANCE_TOKEN for the instance named "my-instance"
+func (cr *credentialResolver) getEnvName(field SecretField) string {
+	instance := strings.Trim(nonEnvChars.ReplaceAllString(strings.ToUpper(cr.config.Name), "_"), "_")
+
+	return fmt.Sprintf("Y2C_%s_%s", instance, field.Env)
+}
+
+func (cr *credentialResolver) decrypt(field SecretField) (string, error) {
+	// the secret is only required when a value is actually encrypted
+	if cr.secret == "" {
+		secret, err := utils.GetSecret()
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("Could not find .secret to decrypt %s, set Y2C_SECRET or use %s instead", field.Name, cr.getEnvName(field)))
+		}
+		cr.secret = secret
+	}
+
+	decrypted, err := utils.Decrypt(*field.Value, cr.secret)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Could not decrypt %s, %s", field.Name, err.Error()))
+	}
+
+	return decrypted, nil
+}
+
+func (cr *credentialResolver) readTokenFile(file string) (string, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(cr.configDir, file)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Could not read token_file %s\n%s", file, err.Error()))
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// runTokenCommand runs the command with the system shell, e.g. "pass show confluence/token",
+// stderr is passed through so the command can prompt for a passphrase
+func runTokenCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("token_command '%s' failed\n%s", command, err.Error()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,65 @@
+package confluence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveCredentialsEnv(t *testing.T) {
+	t.Setenv("Y2C_MY_INSTANCE_TOKEN", "from-env")
+
+	ic := InstanceConfig{Name: "my-instance", Type: "cloud", API_token: "AES_ENC:not-decrypted"}
+	if err := resolveCredentials(&ic, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ic.API_token != "from-env" {
+		t.Errorf("Expected token from environment, got '%s'", ic.API_token)
+	}
+}
+
+func TestResolveCredentialsFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "token"), []byte("from-file\n"), 0600)
+
+	ic := InstanceConfig{Name: "file", Type: "server", Token_file: "token"}
+	if err := resolveCredentials(&ic, dir); err != nil {
+		t.Fatal(err)
+	}
+	if ic.Password != "from-file" {
+		t.Errorf("Expected password from token_file, got '%s'", ic.Password)
+	}
+}
+
+func TestResolveCredentialsCommand(t *testing.T) {
+	ic := InstanceConfig{Name: "cmd", Auth_type: AUTH_BEARER, Token_command: "echo from-command"}
+	if err := resolveCredentials(&ic, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ic.Token != "from-command" {
+		t.Errorf("Expected token from token_command, got '%s'", ic.Token)
+	}
+}
+
+func TestResolveCredentialsPlainText(t *testing.T) {
+	ic := InstanceConfig{Name: "oauth", Auth_type: AUTH_OAUTH, OAuth: OAuthConfig{ClientSecret: "plain"}}
+	if err := resolveCredentials(&ic, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ic.OAuth.ClientSecret != "plain" || ic.OAuth.RefreshToken != "" {
+		t.Errorf("Unexpected OAuth credentials %+v", ic.OAuth)
+	}
+}
+
+func TestResolveCredentialsMissing(t *testing.T) {
+	ic := InstanceConfig{Name: "missing", Type: "cloud"}
+	err := resolveCredentials(&ic, "")
+	if err == nil {
+		t.Fatal("Expected an error for a missing API token")
+	}
+
+	expected := "No API token configured, set Y2C_MISSING_TOKEN, token_command, token_file or api_token in config.yml"
+	if err.Error() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, err.Error())
+	}
+}
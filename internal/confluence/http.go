@@ -0,0 +1,101 @@
+package confluence
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const DEFAULT_HTTP_TIMEOUT = 10 * time.Second
+
+type HttpConfig struct {
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Proxy URL, when empty the HTTP_PROXY/HTTPS_PROXY environment variables are used
+	Proxy string `yaml:"proxy,omitempty"`
+	// CABundle is a PEM file of certificate authorities trusted in addition to the system pool
+	CABundle string `yaml:"ca_bundle,omitempty"`
+	// ClientCert and ClientKey are PEM files used for mutual TLS
+	ClientCert         string            `yaml:"client_cert,omitempty"`
+	ClientKey          string            `yaml:"client_key,omitempty"`
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify,omitempty"`
+	Headers            map[string]string `yaml:"headers,omitempty"`
+}
+
+// resolvePaths makes the certificate paths relative to the directory of the config file
+func (hc *HttpConfig) resolvePaths(configDir string) {
+	for _, path := range []*string{&hc.CABundle, &hc.ClientCert, &hc.ClientKey} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(configDir, *path)
+		}
+	}
+}
+
+func newHttpClient(hc HttpConfig) (http.Client, error) {
+	timeout := hc.Timeout
+	if timeout <= 0 {
+		timeout = DEFAULT_HTTP_TIMEOUT
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if hc.Proxy != "" {
+		proxyUrl, err := url.Parse(hc.Proxy)
+		if err != nil {
+			return http.Client{}, errors.New(fmt.Sprintf("Invalid proxy URL %s\n%s", hc.Proxy, err.Error()))
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	tlsConfig, err := newTlsConfig(hc)
+	if err != nil {
+		return http.Client{}, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}, nil
+}
+
+func newTlsConfig(hc HttpConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: hc.InsecureSkipVerify,
+	}
+
+	if hc.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		pem, err := os.ReadFile(hc.CABundle)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Could not read CA bundle %s\n%s", hc.CABundle, err.Error()))
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New(fmt.Sprintf("No certificates found in CA bundle %s", hc.CABundle))
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if hc.ClientCert != "" || hc.ClientKey != "" {
+		if hc.ClientCert == "" || hc.ClientKey == "" {
+			return nil, errors.New("Both client_cert and client_key are required for mutual TLS")
+		}
+
+		cert, err := tls.LoadX509KeyPair(hc.ClientCert, hc.ClientKey)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Could not load client certificate %s\n%s", hc.ClientCert, err.Error()))
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,101 @@
+package confluence
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DEFAULT_MAX_RETRIES   = 4
+	DEFAULT_INITIAL_DELAY = time.Second
+	DEFAULT_MAX_DELAY     = 30 * time.Second
+)
+
+type RetryConfig struct {
+	// MaxRetries is the number of times a failed request is retried, a negative value disables retries
+	MaxRetries   int           `yaml:"max_retries,omitempty"`
+	InitialDelay time.Duration `yaml:"initial_delay,omitempty"`
+	MaxDelay     time.Duration `yaml:"max_delay,omitempty"`
+}
+
+func (rc *RetryConfig) setDefaults() {
+	if rc.MaxRetries == 0 {
+		rc.MaxRetries = DEFAULT_MAX_RETRIES
+	}
+	if rc.InitialDelay <= 0 {
+		rc.InitialDelay = DEFAULT_INITIAL_DELAY
+	}
+	if rc.MaxDelay <= 0 {
+		rc.MaxDelay = DEFAULT_MAX_DELAY
+	}
+}
+
+// shouldRetry decides if a request can safely be sent again.
+// Rate limited requests (429) were rejected before being processed, so they are always retried.
+// Network errors and 502/503/504 responses are only retried for idempotent methods,
+// as a POST may have created content before the error occurred.
+func shouldRetry(method string, resp *http.Response, err error) bool {
+	idempotent := method == "GET" || method == "PUT" || method == "DELETE"
+
+	if resp == nil {
+		return err != nil && idempotent
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	}
+
+	return false
+}
+
+// getRetryDelay honours the Retry-After header when present, up to MaxDelay, otherwise the delay grows
+// exponentially from InitialDelay up to MaxDelay, with jitter so concurrent workers do not retry in lockstep
+func (rc RetryConfig) getRetryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if delay > rc.MaxDelay {
+				delay = rc.MaxDelay
+			}
+			return delay
+		}
+	}
+
+	delay := rc.InitialDelay << attempt
+	if delay > rc.MaxDelay || delay <= 0 {
+		delay = rc.MaxDelay
+	}
+
+	// full jitter in the upper half of the delay
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// parseRetryAfter supports both forms of the header, delay in seconds and HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		// a value too large for a duration is kept at the longest one
+		if int64(seconds) > int64(math.MaxInt64/time.Second) {
+			return time.Duration(math.MaxInt64), true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,100 @@
+package confluence
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestApiService(handler http.HandlerFunc) (ConfluenceApiService, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	config := InstanceConfig{
+		Protocol:   "http",
+		Host:       strings.TrimPrefix(server.URL, "http://"),
+		API_prefix: "/rest/api",
+		Retry:      RetryConfig{InitialDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	}
+	config.Retry.setDefaults()
+
+	return NewConfluenceApiService("DEMO", config), server
+}
+
+func TestRequestRetriesRateLimit(t *testing.T) {
+	calls := 0
+	api, server := newTestApiService(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	if _, err := api.request("POST", "/content", nil); err != nil {
+		t.Fatalf("Expected request to succeed after retries, got %s", err.Error())
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", calls)
+	}
+	if api.GetRetryCount() != 2 {
+		t.Fatalf("Expected a retry count of 2, got %d", api.GetRetryCount())
+	}
+}
+
+func TestRequestDoesNotRetryUnsafePost(t *testing.T) {
+	calls := 0
+	api, server := newTestApiService(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer server.Close()
+
+	if _, err := api.request("POST", "/content", nil); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if calls != 1 {
+		t.Fatalf("Expected POST to not be retried on 503, got %d calls", calls)
+	}
+
+	calls = 0
+	if _, err := api.request("GET", "/content", nil); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if calls != DEFAULT_MAX_RETRIES+1 {
+		t.Fatalf("Expected GET to be retried %d times on 503, got %d calls", DEFAULT_MAX_RETRIES, calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if delay, ok := parseRetryAfter("5"); !ok || delay != 5*time.Second {
+		t.Fatalf("Expected 5s, got %s", delay)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Fatal("Expected invalid Retry-After to be ignored")
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(date); !ok || delay <= 0 || delay > time.Minute {
+		t.Fatalf("Expected a delay of up to 1m, got %s", delay)
+	}
+}
+
+func TestRetryAfterIsCappedByMaxDelay(t *testing.T) {
+	rc := RetryConfig{InitialDelay: time.Second, MaxDelay: 30 * time.Second}
+	resp := &http.Response{Header: http.Header{}}
+
+	for _, value := range []string{"3600", "99999999999999999"} {
+		resp.Header.Set("Retry-After", value)
+		if delay := rc.getRetryDelay(0, resp); delay != rc.MaxDelay {
+			t.Errorf("Expected Retry-After %s to be capped at %s, got %s", value, rc.MaxDelay, delay)
+		}
+	}
+
+	resp.Header.Set("Retry-After", "2")
+	if delay := rc.getRetryDelay(0, resp); delay != 2*time.Second {
+		t.Errorf("Expected Retry-After 2 to be honoured, got %s", delay)
+	}
+}
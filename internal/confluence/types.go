@@ -6,8 +6,26 @@ package confluence
 
 // Space
 type ConfluenceSpacePayload struct {
-	Key  string `json:"key"`
-	Name string `json:"name"`
+	Key         string            `json:"key"`
+	Name        string            `json:"name"`
+	Description *SpaceDescription `json:"description,omitempty"`
+}
+type SpaceDescription struct {
+	Plain Storage `json:"plain"`
+}
+
+// Space Permission
+type ConfluenceSpacePermissionPayload struct {
+	Subject   PermissionSubject   `json:"subject"`
+	Operation PermissionOperation `json:"operation"`
+}
+type PermissionSubject struct {
+	Type       string `json:"type"`
+	Identifier string `json:"identifier"`
+}
+type PermissionOperation struct {
+	Key    string `json:"key"`
+	Target string `json:"target"`
 }
 
 // Content
@@ -28,7 +46,8 @@ type Storage struct {
 	Representation string `json:"representation"`
 }
 type Body struct {
-	Storage Storage `json:"storage"`
+	Storage        *Storage `json:"storage,omitempty"`
+	AtlasDocFormat *Storage `json:"atlas_doc_format,omitempty"`
 }
 type PageId struct {
 	Id string `json:"id"`
@@ -61,19 +80,50 @@ type Version struct {
 	MinorEdit bool `json:"minorEdit"`
 }
 
+// Page (v2)
+type ConfluencePageV2Payload struct {
+	Id       string     `json:"id,omitempty"`
+	SpaceId  string     `json:"spaceId"`
+	Status   string     `json:"status"`
+	Title    string     `json:"title"`
+	ParentId string     `json:"parentId,omitempty"`
+	Body     Storage    `json:"body"`
+	Version  *VersionV2 `json:"version,omitempty"`
+}
+type VersionV2 struct {
+	Number  int    `json:"number"`
+	Message string `json:"message,omitempty"`
+}
+
+// Content Properties (v2)
+type ConfluencePropertyV2Payload struct {
+	Key     string     `json:"key"`
+	Value   string     `json:"value"`
+	Version *VersionV2 `json:"version,omitempty"`
+}
+
 //---------------------
 // RESPONSES
 //---------------------
 
-// Content (Create and Update)
+// Content (Create, Update and Get)
 type ConfluenceContentResponse struct {
-	Id    string
+	Id   string
+	Body struct {
+		Storage Storage
+	}
 	Links struct {
 		Webui string
 		Base  string
 	} `json:"_links"`
 }
 
+// Content Body (Convert)
+type ConfluenceContentBodyResponse struct {
+	Value          string
+	Representation string
+}
+
 // Search Results
 type ConfluenceSearchResultsResponse struct {
 	Results []ConfluencePageExpanded
@@ -85,15 +135,21 @@ type ConfluenceSearchResultsResponse struct {
 }
 type ConfluencePageExpanded struct {
 	ConfluencePage
-	Version   Version
+	Version struct {
+		Number int
+		By     ConfluenceUser
+	}
 	Ancestors []ConfluencePage
-	Metadata  struct {
+	Body      struct {
+		Storage Storage
+	}
+	Metadata struct {
 		Properties struct {
-			Sha256 struct {
-				Id      string
-				Value   string
-				Version Version
-			}
+			Sha256           ConfluenceProperty
+			Attachments      ConfluenceProperty
+			PublishedVersion ConfluenceProperty `json:"published_version"`
+			ResourceId       ConfluenceProperty `json:"resource_id"`
+			Position         ConfluenceProperty
 		}
 		Labels struct {
 			Results []Label
@@ -107,8 +163,79 @@ type ConfluencePage struct {
 	Id    string
 	Title string
 }
+type ConfluenceProperty struct {
+	Id      string
+	Value   string
+	Version Version
+}
+
+// User (Current)
+type ConfluenceUser struct {
+	AccountId string
+	Username  string
+}
+
+// Attachments (List, Create or Update)
+type ConfluenceAttachmentsResponse struct {
+	Results []ConfluenceAttachment
+	Links   struct {
+		Base string
+	} `json:"_links"`
+}
+type ConfluenceAttachment struct {
+	Id    string
+	Title string
+	Links struct {
+		Download string
+	} `json:"_links"`
+}
+
 type ConfluenceSpaceResponse struct {
+	Name        string
+	Type        string
+	Description struct {
+		Plain Storage
+	}
 	Homepage struct {
 		Id string
 	}
+	Permissions []ConfluenceSpacePermission
+}
+type ConfluenceSpacePermission struct {
+	Id       int
+	Subjects struct {
+		User struct {
+			Results []ConfluenceUser
+		}
+		Group struct {
+			Results []struct {
+				Name string
+			}
+		}
+	}
+	Operation struct {
+		Operation  string
+		TargetType string
+	}
+}
+
+// Page (v2)
+type ConfluencePageV2Response struct {
+	Id   string
+	Body struct {
+		Storage Storage
+	}
+	Links struct {
+		Webui string
+		Base  string
+	} `json:"_links"`
+}
+
+// Spaces (v2)
+type ConfluenceSpacesV2Response struct {
+	Results []struct {
+		Id         string
+		Key        string
+		HomepageId string
+	}
 }
@@ -4,4 +4,5 @@ const (
 	GENERATED_BY_LABEL = "generated_by=y2c"
 	COMMAND_NOT_FOUND  = `"%s" command not found`
 	DUPLICATE_TITLE    = `Duplicate title found -- "%s" (%s/) matches "%s" (%s/)`
+	DUPLICATE_ID       = `Duplicate id found -- "%s" is used by %s and %s`
 )
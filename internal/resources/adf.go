@@ -0,0 +1,232 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// adfNode describes what the ADF schema allows for a node type
+type adfNode struct {
+	// leaf nodes can't have content
+	leaf  bool
+	attrs []string
+	// enums restricts the values of string attributes
+	enums map[string][]string
+}
+
+var adfNodes = map[string]adfNode{
+	// block nodes
+	"blockquote":      {},
+	"bulletList":      {},
+	"codeBlock":       {},
+	"decisionList":    {},
+	"decisionItem":    {},
+	"expand":          {},
+	"nestedExpand":    {},
+	"heading":         {attrs: []string{"level"}},
+	"layoutSection":   {},
+	"layoutColumn":    {attrs: []string{"width"}},
+	"listItem":        {},
+	"mediaGroup":      {},
+	"mediaSingle":     {},
+	"orderedList":     {},
+	"panel":           {attrs: []string{"panelType"}, enums: map[string][]string{"panelType": {"info", "note", "tip", "warning", "error", "success", "custom"}}},
+	"paragraph":       {},
+	"rule":            {leaf: true},
+	"table":           {},
+	"tableRow":        {},
+	"tableHeader":     {},
+	"tableCell":       {},
+	"taskList":        {attrs: []string{"localId"}},
+	"taskItem":        {attrs: []string{"localId", "state"}, enums: map[string][]string{"state": {"TODO", "DONE"}}},
+	"blockCard":       {leaf: true},
+	"embedCard":       {leaf: true, attrs: []string{"url", "layout"}},
+	"extension":       {leaf: true, attrs: []string{"extensionType", "extensionKey"}},
+	"bodiedExtension": {attrs: []string{"extensionType", "extensionKey"}},
+	// inline nodes
+	"text":            {leaf: true},
+	"hardBreak":       {leaf: true},
+	"date":            {leaf: true, attrs: []string{"timestamp"}},
+	"emoji":           {leaf: true, attrs: []string{"shortName"}},
+	"inlineCard":      {leaf: true},
+	"inlineExtension": {leaf: true, attrs: []string{"extensionType", "extensionKey"}},
+	"media":           {leaf: true, attrs: []string{"type"}},
+	"mediaInline":     {leaf: true, attrs: []string{"id"}},
+	"mention":         {leaf: true, attrs: []string{"id"}},
+	"placeholder":     {leaf: true, attrs: []string{"text"}},
+	"status":          {leaf: true, attrs: []string{"text", "color"}, enums: map[string][]string{"color": {"neutral", "purple", "blue", "red", "yellow", "green"}}},
+}
+
+var adfMarks = map[string][]string{
+	"alignment":       {"align"},
+	"annotation":      {"id", "annotationType"},
+	"backgroundColor": {"color"},
+	"border":          {"size", "color"},
+	"breakout":        {"mode"},
+	"code":            {},
+	"dataConsumer":    {"sources"},
+	"em":              {},
+	"fragment":        {"localId"},
+	"indentation":     {"level"},
+	"link":            {"href"},
+	"strike":          {},
+	"strong":          {},
+	"subsup":          {"type"},
+	"textColor":       {"color"},
+	"underline":       {},
+}
+
+// ValidateAdf checks an Atlassian Document Format document against the node and mark types of the ADF schema.
+// The document is returned compacted, so formatting doesn't change its sha256.
+func ValidateAdf(document string) (string, error) {
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return "", errors.New(fmt.Sprintf("Invalid ADF, the document is not a JSON object\n%s", err.Error()))
+	}
+
+	if doc["type"] != "doc" {
+		return "", errors.New(fmt.Sprintf("Invalid ADF, expected the root node to be of type doc, got %v", doc["type"]))
+	}
+	if version, ok := doc["version"].(json.Number); !ok || version.String() != "1" {
+		return "", errors.New(fmt.Sprintf("Invalid ADF, expected doc version 1, got %v", doc["version"]))
+	}
+	if err := validateAdfContent("content", doc["content"], true); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(document)); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func validateAdfContent(path string, content interface{}, required bool) error {
+	if content == nil && !required {
+		return nil
+	}
+
+	nodes, ok := content.([]interface{})
+	if !ok {
+		return adfError(path, "expected an array of nodes")
+	}
+
+	for i, n := range nodes {
+		if err := validateAdfNode(fmt.Sprintf("%s[%d]", path, i), n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateAdfNode(path string, n interface{}) error {
+	node, ok := n.(map[string]interface{})
+	if !ok {
+		return adfError(path, "expected a node object")
+	}
+
+	nodeType, _ := node["type"].(string)
+	spec, exists := adfNodes[nodeType]
+	if !exists {
+		return adfError(path, fmt.Sprintf("unknown node type '%v'", node["type"]))
+	}
+
+	if nodeType == "text" {
+		if text, _ := node["text"].(string); text == "" {
+			return adfError(path, "text nodes require a non-empty text")
+		}
+	}
+
+	if err := validateAdfAttrs(path, node["attrs"], spec.attrs, spec.enums); err != nil {
+		return err
+	}
+
+	if err := validateAdfMarks(path+".marks", node["marks"]); err != nil {
+		return err
+	}
+
+	if spec.leaf {
+		if node["content"] != nil {
+			return adfError(path, fmt.Sprintf("%s nodes can't have content", nodeType))
+		}
+		return nil
+	}
+
+	return validateAdfContent(path+".content", node["content"], false)
+}
+
+func validateAdfMarks(path string, m interface{}) error {
+	if m == nil {
+		return nil
+	}
+
+	marks, ok := m.([]interface{})
+	if !ok {
+		return adfError(path, "expected an array of marks")
+	}
+
+	for i, mk := range marks {
+		markPath := fmt.Sprintf("%s[%d]", path, i)
+		mark, ok := mk.(map[string]interface{})
+		if !ok {
+			return adfError(markPath, "expected a mark object")
+		}
+
+		markType, _ := mark["type"].(string)
+		attrs, exists := adfMarks[markType]
+		if !exists {
+			return adfError(markPath, fmt.Sprintf("unknown mark type '%v'", mark["type"]))
+		}
+		if err := validateAdfAttrs(markPath, mark["attrs"], attrs, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateAdfAttrs(path string, a interface{}, required []string, enums map[string][]string) error {
+	attrs, ok := a.(map[string]interface{})
+	if a != nil && !ok {
+		return adfError(path+".attrs", "expected an object")
+	}
+
+	for _, name := range required {
+		if _, exists := attrs[name]; !exists {
+			return adfError(path+".attrs", fmt.Sprintf("missing required attribute %s", name))
+		}
+	}
+
+	for name, values := range enums {
+		value, exists := attrs[name]
+		if !exists {
+			continue
+		}
+		if !isOneOf(fmt.Sprint(value), values) {
+			return adfError(path+".attrs."+name, fmt.Sprintf("'%v' is not one of %v", value, values))
+		}
+	}
+
+	return nil
+}
+
+func isOneOf(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func adfError(path string, message string) error {
+	return errors.New(fmt.Sprintf("Invalid ADF at %s, %s", path, message))
+}
@@ -0,0 +1,82 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAdf(t *testing.T) {
+	document := `{
+  "type": "doc",
+  "version": 1,
+  "content": [
+    {"type": "panel", "attrs": {"panelType": "info"}, "content": [
+      {"type": "paragraph", "content": [
+        {"type": "text", "text": "Status ", "marks": [{"type": "strong"}]},
+        {"type": "status", "attrs": {"text": "DONE", "color": "green"}}
+      ]}
+    ]},
+    {"type": "expand", "attrs": {"title": "Details"}, "content": [{"type": "rule"}]}
+  ]
+}`
+
+	adf, err := ValidateAdf(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(adf, "\n") {
+		t.Errorf("Expected a compacted document, got\n%s", adf)
+	}
+}
+
+func TestValidateAdfErrors(t *testing.T) {
+	documents := map[string]string{
+		`{"type":"paragraph","version":1,"content":[]}`:                                                  "root node to be of type doc",
+		`{"type":"doc","content":[]}`:                                                                    "doc version 1",
+		`{"type":"doc","version":1,"content":[{"type":"unknown"}]}`:                                      "content[0], unknown node type 'unknown'",
+		`{"type":"doc","version":1,"content":[{"type":"paragraph","content":[{"type":"text"}]}]}`:        "content[0].content[0], text nodes require a non-empty text",
+		`{"type":"doc","version":1,"content":[{"type":"panel","attrs":{"panelType":"red"}}]}`:            "content[0].attrs.panelType, 'red' is not one of",
+		`{"type":"doc","version":1,"content":[{"type":"rule","content":[]}]}`:                            "rule nodes can't have content",
+		`{"type":"doc","version":1,"content":[{"type":"paragraph","marks":[{"type":"link"}]}]}`:          "content[0].marks[0].attrs, missing required attribute href",
+		`{"type":"doc","version":1,"content":[{"type":"status","attrs":{"text":"A","color":"orange"}}]}`: "'orange' is not one of",
+	}
+
+	for document, expected := range documents {
+		_, err := ValidateAdf(document)
+		if err == nil {
+			t.Errorf("Expected %s to be invalid", document)
+		} else if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q, got %q", expected, err.Error())
+		}
+	}
+}
+
+func TestRenderAdfContent(t *testing.T) {
+	yr := NewYamlResource("/page.yml", unmarshal([]byte("kind: adf\ntitle: Page\nadf:\n  type: doc\n  version: 1\n  content:\n    - type: rule\n")))
+	page := NewPage(yr.Path, yr)
+
+	if err := renderContent(page, "{{{adf}}}", ADF_REPRESENTATION, "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if page.Content.Markup != `{"content":[{"type":"rule"}],"type":"doc","version":1}` {
+		t.Errorf("Unexpected ADF %s", page.Content.Markup)
+	}
+	if page.GetRepresentation() != ADF_REPRESENTATION {
+		t.Errorf("Expected representation %s, got %s", ADF_REPRESENTATION, page.GetRepresentation())
+	}
+
+	if err := renderContent(page, "{{{adf}}}", ADF_REPRESENTATION, "header", "", nil); err == nil {
+		t.Errorf("Expected hook headers to be rejected")
+	}
+}
+
+func TestRenderContentReturnsTemplateErrors(t *testing.T) {
+	yr := NewYamlResource("/page.yml", unmarshal([]byte("kind: adf\ntitle: Page\nadf: '{}'\n")))
+	page := NewPage(yr.Path, yr)
+
+	for _, representation := range []string{WIKI_REPRESENTATION, ADF_REPRESENTATION} {
+		if err := renderContent(page, "{{#unclosed}}x", representation, "", "", nil); err == nil {
+			t.Errorf("Expected the error of the template to be returned for %s", representation)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mattn/go-zglob"
+)
+
+// the content property of a page holding the id and sha256 of every attachment y2c uploaded
+const ATTACHMENTS_PROPERTY = "attachments"
+
+type LocalAttachment struct {
+	Name   string
+	Path   string
+	Sha256 string
+}
+
+type RemoteAttachment struct {
+	Id     string `json:"id"`
+	Sha256 string `json:"sha256"`
+}
+
+type RemoteAttachments struct {
+	Id      string
+	Value   map[string]RemoteAttachment
+	Version int
+}
+
+type AttachmentChange struct {
+	Operation ChangeType
+	Name      string
+	Local     *LocalAttachment
+	Remote    *RemoteAttachment
+}
+
+// ParseAttachmentsProperty reads the value of the attachments property, an invalid value is treated as no attachments
+func ParseAttachmentsProperty(value string) map[string]RemoteAttachment {
+	attachments := map[string]RemoteAttachment{}
+	if value != "" {
+		json.Unmarshal([]byte(value), &attachments)
+	}
+
+	return attachments
+}
+
+// ResolveAttachments finds the files matching the attachment patterns of a resource, relative to dir
+func ResolveAttachments(dir string, patterns []string) ([]LocalAttachment, error) {
+	attachments := []LocalAttachment{}
+	names := map[string]string{}
+
+	for _, pattern := range patterns {
+		matches, err := zglob.Glob(filepath.Join(dir, pattern))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, errors.New(fmt.Sprintf("No files found for attachment %s", pattern))
+		}
+
+		for _, match := range matches {
+			if stat, err := os.Stat(match); err != nil || stat.IsDir() {
+				continue
+			}
+
+			name := filepath.Base(match)
+			if other, exists := names[name]; exists {
+				if other == match {
+					continue
+				}
+				return nil, errors.New(fmt.Sprintf("Attachments %s and %s have the same file name", other, match))
+			}
+			names[name] = match
+
+			sha, err := hashFile(match)
+			if err != nil {
+				return nil, err
+			}
+			attachments = append(attachments, LocalAttachment{Name: name, Path: match, Sha256: sha})
+		}
+	}
+
+	sort.SliceStable(attachments, func(i, j int) bool {
+		return attachments[i].Name < attachments[j].Name
+	})
+
+	return attachments, nil
+}
+
+func hashFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (p *Page) getRemoteAttachments() map[string]RemoteAttachment {
+	if p.Remote == nil || p.Remote.Attachments.Value == nil {
+		return map[string]RemoteAttachment{}
+	}
+
+	return p.Remote.Attachments.Value
+}
+
+// GetAttachmentChanges returns the attachments to upload or delete, ordered by file name. Unchanged attachments are left out.
+func (p *Page) GetAttachmentChanges() []AttachmentChange {
+	changes := []AttachmentChange{}
+	remotes := p.getRemoteAttachments()
+	local := map[string]bool{}
+
+	for i := range p.Attachments {
+		attachment := &p.Attachments[i]
+		local[attachment.Name] = true
+
+		remote, exists := remotes[attachment.Name]
+		if !exists {
+			changes = append(changes, AttachmentChange{Operation: CREATE, Name: attachment.Name, Local: attachment})
+		} else if remote.Sha256 != attachment.Sha256 {
+			changes = append(changes, AttachmentChange{Operation: UPDATE, Name: attachment.Name, Local: attachment, Remote: &remote})
+		}
+	}
+
+	for name, remote := range remotes {
+		if !local[name] {
+			remote := remote
+			changes = append(changes, AttachmentChange{Operation: DELETE, Name: name, Remote: &remote})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
+
+func (p *Page) AttachmentsDiffer() bool {
+	// pages that are deleted take their attachments with them
+	if p.Resource == nil || p.Remote == nil {
+		return false
+	}
+
+	return len(p.GetAttachmentChanges()) > 0
+}
+
+// GetAttachmentsProperty creates the attachments property recording the uploaded attachments
+func (p *Page) GetAttachmentsProperty(attachments map[string]RemoteAttachment) Property {
+	propertyId := ""
+	version := 0
+	if p.Remote != nil {
+		propertyId = p.Remote.Attachments.Id
+		version = p.Remote.Attachments.Version
+	}
+
+	value, err := json.Marshal(attachments)
+	if err != nil {
+		panic(err)
+	}
+
+	return NewProperty(p.GetRemoteId(), propertyId, ATTACHMENTS_PROPERTY, string(value), version)
+}
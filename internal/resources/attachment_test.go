@@ -0,0 +1,69 @@
+package resources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
+)
+
+func TestResolveAttachments(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "specs"), 0755)
+	os.WriteFile(filepath.Join(dir, "diagram.png"), []byte("png"), 0644)
+	os.WriteFile(filepath.Join(dir, "specs", "b.pdf"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(dir, "specs", "a.pdf"), []byte("a"), 0644)
+
+	attachments, err := ResolveAttachments(dir, []string{"diagram.png", "specs/*.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(getAttachmentNames(attachments)) != "[a.pdf b.pdf diagram.png]" {
+		t.Errorf("Unexpected attachments %v", getAttachmentNames(attachments))
+	}
+	if attachments[0].Sha256 != "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb" {
+		t.Errorf("Unexpected sha256 %s", attachments[0].Sha256)
+	}
+
+	if _, err := ResolveAttachments(dir, []string{"missing.png"}); err == nil {
+		t.Errorf("Expected a missing attachment to fail")
+	}
+	os.WriteFile(filepath.Join(dir, "a.pdf"), []byte("a"), 0644)
+	if _, err := ResolveAttachments(dir, []string{"a.pdf", "specs/a.pdf"}); err == nil {
+		t.Errorf("Expected attachments with the same file name to fail")
+	}
+}
+
+func TestGetAttachmentChanges(t *testing.T) {
+	page := NewPage("/page.yml", NewYamlResource("/page.yml", createYamlNode("wiki", "page")))
+	page.Attachments = []LocalAttachment{{Name: "changed.png", Sha256: "2"}, {Name: "new.png", Sha256: "3"}, {Name: "same.png", Sha256: "4"}}
+	page.Remote = &RemoteResource{Id: "1", Title: "page", Labels: []string{constants.GENERATED_BY_LABEL}, Attachments: RemoteAttachments{Value: ParseAttachmentsProperty(
+		`{"changed.png":{"id":"10","sha256":"1"},"removed.png":{"id":"11","sha256":"5"},"same.png":{"id":"12","sha256":"4"}}`,
+	)}}
+
+	changes := page.GetAttachmentChanges()
+	actual := []string{}
+	for _, c := range changes {
+		actual = append(actual, fmt.Sprintf("%d:%s", c.Operation, c.Name))
+	}
+	expected := fmt.Sprintf("[%d:changed.png %d:new.png %d:removed.png]", UPDATE, CREATE, DELETE)
+	if fmt.Sprint(actual) != expected {
+		t.Errorf("Expected %s, got %v", expected, actual)
+	}
+
+	pu := createPageUpdate(page)
+	if pu.Operation != UPDATE || !pu.IsAttachmentsOnly() {
+		t.Errorf("Expected an attachments only update")
+	}
+}
+
+func getAttachmentNames(attachments []LocalAttachment) []string {
+	names := []string{}
+	for _, a := range attachments {
+		names = append(names, a.Name)
+	}
+
+	return names
+}
@@ -1,7 +1,10 @@
 package resources
 
 var templates map[string]string = map[string]string{
-	"wiki": "{{{markup}}}",
+	"wiki":          "{{{markup}}}",
+	"storage.xhtml": "{{{markup}}}",
+	"markdown.md":   "{{{markdown}}}",
+	"adf.adf":       "{{{adf}}}",
 }
 
 var hooks map[string]string = map[string]string{
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	. "github.com/flant/libjq-go"
 	"github.com/flant/libjq-go/pkg/jq"
@@ -21,6 +22,9 @@ type HookProcessor struct {
 	kindHooks        map[string]*Hook
 	patternHooks     []*Hook
 	lsCache          *LsCache
+	// hookSets caches the hook set of each kind, so jq statements are precompiled once
+	hookSets map[string]HookSet
+	mu       sync.Mutex
 }
 
 type Hook struct {
@@ -58,18 +62,26 @@ type HookSet struct {
 	Header string
 	Footer string
 }
+
+// LsCache holds the files listed by hooks. The files are listed relative to the SPACE_DIR, the cache is kept
+// per space so the hooks can be shared by several spaces.
 type LsCache struct {
-	store map[ListFiles]string
+	store map[lsCacheKey]string
+}
+
+type lsCacheKey struct {
+	spaceDir string
+	files    ListFiles
 }
 
 func (c *LsCache) Get(lf ListFiles) (string, bool) {
-	val, exists := c.store[lf]
+	val, exists := c.store[lsCacheKey{os.Getenv("SPACE_DIR"), lf}]
 
 	return val, exists
 }
 
 func (c *LsCache) Set(lf ListFiles, data string) {
-	c.store[lf] = data
+	c.store[lsCacheKey{os.Getenv("SPACE_DIR"), lf}] = data
 }
 
 type Ls struct {
@@ -123,8 +135,9 @@ func NewHookProcessor(hooksDir string, precompile bool) *HookProcessor {
 		hooks:            map[string]*Hook{},
 		kindHooks:        map[string]*Hook{},
 		lsCache: &LsCache{
-			store: map[ListFiles]string{},
+			store: map[lsCacheKey]string{},
 		},
+		hookSets: map[string]HookSet{},
 	}
 
 	hooks := append(loadHooks(hooksDir))
@@ -175,6 +188,13 @@ func (hp *HookProcessor) GetAll() []*Hook {
 }
 
 func (hp *HookProcessor) GetHookSet(kind string) HookSet {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	if hookset, exists := hp.hookSets[kind]; exists {
+		return hookset
+	}
+
 	hookset := HookSet{}
 	headers := []string{}
 	footers := []string{}
@@ -214,6 +234,7 @@ func (hp *HookProcessor) GetHookSet(kind string) HookSet {
 
 	hookset.Header += strings.Join(headers, "\n")
 	hookset.Footer += strings.Join(footers, "\n")
+	hp.hookSets[kind] = hookset
 
 	return hookset
 }
@@ -0,0 +1,227 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+)
+
+// LINK_SECTION is the mustache section rendering a link to the page of a resource. The path is relative to the
+// resource, or to the space directory when it starts with /, and may point into another space, an alias can
+// follow it, e.g. {{#y2c_link}}../apps/app1.yml|the first app{{/y2c_link}}. The name is prefixed so it doesn't
+// shadow the fields of resources.
+const LINK_SECTION = "y2c_link"
+
+// the text of link sections is wrapped in markers by the template and replaced by the link once rendered, so
+// links only rely on plain sections and tags inside of them are rendered in the context of the section
+const (
+	linkStart = "\x00y2c-link:"
+	linkEnd   = "\x00"
+)
+
+var linkSectionOpen = regexp.MustCompile(`\{\{#\s*` + LINK_SECTION + `\s*\}\}`)
+var linkSectionClose = regexp.MustCompile(`\{\{/\s*` + LINK_SECTION + `\s*\}\}`)
+var renderedLink = regexp.MustCompile(`(?s)` + linkStart + `(.*?)` + linkEnd)
+
+// linkRenderer renders the links of a single page
+type linkRenderer struct {
+	rt             *RenderTools
+	page           *Page
+	representation string
+}
+
+// renderTemplate renders a template whose link sections are resolved by links, links can be nil
+func renderTemplate(template string, links *linkRenderer, context ...interface{}) (string, error) {
+	if links == nil {
+		return mustache.Render(template, context...)
+	}
+
+	template = linkSectionOpen.ReplaceAllStringFunc(template, func(open string) string { return open + linkStart })
+	template = linkSectionClose.ReplaceAllStringFunc(template, func(close string) string { return linkEnd + close })
+
+	// the section is always rendered, it takes precedence over a y2c_link field of the resource
+	markup, err := mustache.Render(template, append([]interface{}{map[string]interface{}{LINK_SECTION: true}}, context...)...)
+	if err != nil {
+		return "", err
+	}
+
+	var linkErr error
+	markup = renderedLink.ReplaceAllStringFunc(markup, func(text string) string {
+		link, err := links.render(html.UnescapeString(renderedLink.FindStringSubmatch(text)[1]))
+		if err != nil && linkErr == nil {
+			linkErr = err
+		}
+
+		return link
+	})
+
+	return markup, linkErr
+}
+
+func (lr *linkRenderer) render(target string) (string, error) {
+	alias := ""
+	if i := strings.Index(target, "|"); i >= 0 {
+		target, alias = target[:i], strings.TrimSpace(target[i+1:])
+	}
+	target = strings.TrimSpace(target)
+
+	spaceKey, title, err := lr.rt.resolveLink(lr.page, target)
+	if err != nil {
+		return "", err
+	}
+
+	return formatLink(lr.representation, spaceKey, title, alias)
+}
+
+// resolveLink returns the space key and title of the page of the resource at target. The space key is empty for
+// pages of the space being rendered. Those are resolved through the page tree being rendered, other pages by the
+// title of their resource file, the tree of a single file only holds the file and its ancestors.
+func (rt *RenderTools) resolveLink(p *Page, target string) (string, string, error) {
+	if target == "" {
+		return "", "", errors.New("Empty link target")
+	}
+
+	var abs string
+	if filepath.IsAbs(target) {
+		abs = filepath.Join(rt.dirProps.SpaceDir, target)
+	} else {
+		abs = filepath.Join(rt.dirProps.SpaceDir, p.Resource.GetSourceDir(), target)
+	}
+
+	spaceKey := ""
+	spaceDir := rt.dirProps.SpaceDir
+	rel, err := filepath.Rel(spaceDir, abs)
+	if err != nil {
+		return "", "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		spacesDir := filepath.Dir(spaceDir)
+		if rel, err = filepath.Rel(spacesDir, abs); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return "", "", errors.New(fmt.Sprintf("Link target %s is outside of the spaces directory", target))
+		}
+		spaceKey = strings.Split(rel, string(os.PathSeparator))[0]
+		spaceDir = filepath.Join(spacesDir, spaceKey)
+		rel, _ = filepath.Rel(spaceDir, abs)
+	}
+
+	relPath := string(os.PathSeparator)
+	if rel != "." {
+		relPath += rel
+	}
+
+	var title string
+	if page := rt.getLinkPage(spaceKey, relPath); page != nil {
+		title, err = getLinkTitle(page, relPath)
+	} else {
+		title, err = loadLinkTitle(spaceDir, relPath)
+	}
+	if err != nil {
+		return "", "", errors.New(fmt.Sprintf("Link target %s can't be resolved\n%s", target, err.Error()))
+	}
+
+	return spaceKey, title, nil
+}
+
+// getLinkPage returns the page of the resource at relPath in the page tree being rendered, nil when it isn't part
+// of it. Index files link to their directory.
+func (rt *RenderTools) getLinkPage(spaceKey string, relPath string) *Page {
+	if spaceKey != "" || rt.pt == nil {
+		return nil
+	}
+
+	if homepage := rt.pt.GetHomepage(); homepage != nil && (relPath == string(os.PathSeparator) || homepage.Resource.Path == relPath) {
+		return homepage
+	}
+	if isIndexFile(relPath) {
+		relPath = filepath.Dir(relPath)
+	}
+	if page := rt.pt.pages[relPath]; page != nil && page.Resource != nil {
+		return page
+	}
+
+	return nil
+}
+
+// getLinkTitle returns the title of the page, after the hooks ran
+func getLinkTitle(page *Page, relPath string) (string, error) {
+	if page.GetTitle() == "" {
+		return "", errors.New(fmt.Sprintf("%s has no title", relPath))
+	}
+
+	return page.GetTitle(), nil
+}
+
+// loadLinkTitle returns the title of the resource at relPath of a space directory, before any hook ran.
+// Directories are titled by their index file or their name.
+func loadLinkTitle(spaceDir string, relPath string) (string, error) {
+	abs := filepath.Join(spaceDir, relPath)
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("%s does not exist", abs))
+	}
+
+	dir := relPath
+	if !info.IsDir() {
+		dir = filepath.Dir(relPath)
+	}
+	for d := dir; d != string(os.PathSeparator) && d != "."; d = filepath.Dir(d) {
+		if ignoreDir(d) {
+			return "", errors.New(fmt.Sprintf("%s is inside of ignored directory %s", relPath, d))
+		}
+	}
+
+	file := relPath
+	if info.IsDir() {
+		index := findIndexFile(abs)
+		if index == "" {
+			if relPath == string(os.PathSeparator) {
+				return "", errors.New("The homepage has no index file")
+			}
+			return filepath.Base(relPath), nil
+		}
+		file = filepath.Join(relPath, filepath.Base(index))
+	} else if !IsResourceFile(relPath) {
+		return "", errors.New(fmt.Sprintf("%s is not a resource file", relPath))
+	}
+
+	yr := YamlResourceLoader{LoadYaml: DefaultLoadYaml}.LoadYamlResource(spaceDir, file)
+	if yr.Title == "" {
+		return "", errors.New(fmt.Sprintf("%s has no title", file))
+	}
+
+	return yr.Title, nil
+}
+
+// formatLink renders a link to the page titled title in the given representation
+func formatLink(representation string, spaceKey string, title string, alias string) (string, error) {
+	switch representation {
+	case WIKI_REPRESENTATION:
+		target := title
+		if spaceKey != "" {
+			target = spaceKey + ":" + title
+		}
+		if alias != "" {
+			return fmt.Sprintf("[%s|%s]", alias, target), nil
+		}
+		return fmt.Sprintf("[%s]", target), nil
+	case STORAGE_REPRESENTATION, MARKDOWN_REPRESENTATION:
+		// MarkdownToStorage keeps storage format elements, the storage link is passed through
+		page := fmt.Sprintf(`<ri:page ri:content-title="%s"`, html.EscapeString(title))
+		if spaceKey != "" {
+			page += fmt.Sprintf(` ri:space-key="%s"`, html.EscapeString(spaceKey))
+		}
+		body := ""
+		if alias != "" {
+			body = fmt.Sprintf("<ac:plain-text-link-body><![CDATA[%s]]></ac:plain-text-link-body>", strings.ReplaceAll(alias, "]]>", "]]]]><![CDATA[>"))
+		}
+		return fmt.Sprintf("<ac:link>%s />%s</ac:link>", page, body), nil
+	}
+
+	return "", errors.New(fmt.Sprintf("Links are not supported by the %s representation", representation))
+}
@@ -0,0 +1,131 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
+)
+
+func TestResolveLink(t *testing.T) {
+	spacesDir := t.TempDir()
+	spaceDir := filepath.Join(spacesDir, "DEMO")
+	os.MkdirAll(filepath.Join(spaceDir, "apps"), 0755)
+	os.MkdirAll(filepath.Join(spacesDir, "OTHER", "guides"), 0755)
+	os.WriteFile(filepath.Join(spacesDir, "OTHER", "guides", "_index.yml"), []byte("kind: wiki\ntitle: All Guides\n"), 0644)
+	os.WriteFile(filepath.Join(spacesDir, "OTHER", "setup.md"), []byte("# Setup\n"), 0644)
+
+	pt := NewPageTree([]*YamlResource{
+		NewYamlResource("/apps", createYamlNode("wiki", "Applications")),
+		NewYamlResource("/apps/app1.yml", createYamlNode("wiki", "App & 1")),
+		NewYamlResource("/freeform.yml", createYamlNode("wiki", "Freeform")),
+	}, "")
+	rt := &RenderTools{dirProps: utils.DirectoryProperties{SpaceDir: spaceDir}, pt: pt}
+	page := pt.GetPage("/apps/app1.yml")
+
+	tests := []struct {
+		target   string
+		spaceKey string
+		title    string
+	}{
+		{"../freeform.yml", "", "Freeform"},
+		{"/apps/app1.yml", "", "App & 1"},
+		{"_index.yml", "", "Applications"},
+		{"../../OTHER/guides", "OTHER", "All Guides"},
+		{"../../OTHER/setup.md", "OTHER", "setup"},
+	}
+	for _, test := range tests {
+		spaceKey, title, err := rt.resolveLink(page, test.target)
+		if err != nil || spaceKey != test.spaceKey || title != test.title {
+			t.Errorf("Expected %s to resolve to %s:%s, got %s:%s %v", test.target, test.spaceKey, test.title, spaceKey, title, err)
+		}
+	}
+
+	for _, target := range []string{"missing.yml", "../../OTHER/missing.yml", "../../../outside.yml"} {
+		if _, _, err := rt.resolveLink(page, target); err == nil {
+			t.Errorf("Expected %s not to resolve", target)
+		}
+	}
+}
+
+func TestResolveLinkOfSingleFile(t *testing.T) {
+	baseDir := t.TempDir()
+	os.WriteFile(filepath.Join(baseDir, "config.yml"), []byte("name: demo\n"), 0644)
+	os.MkdirAll(filepath.Join(baseDir, "templates"), 0755)
+	spaceDir := filepath.Join(baseDir, "spaces", "DEMO")
+	os.MkdirAll(filepath.Join(spaceDir, "apps"), 0755)
+	os.MkdirAll(filepath.Join(spaceDir, "guides"), 0755)
+	os.WriteFile(filepath.Join(spaceDir, "apps", "_index.yml"), []byte("kind: wiki\ntitle: Applications\n"), 0644)
+	os.WriteFile(filepath.Join(spaceDir, "apps", "app1.yml"), []byte("kind: wiki\ntitle: App 1\n"), 0644)
+	os.WriteFile(filepath.Join(spaceDir, "apps", "app2.yml"), []byte("kind: wiki\ntitle: App 2\n"), 0644)
+	os.WriteFile(filepath.Join(spaceDir, "guides", "setup.yml"), []byte("kind: wiki\ntitle: Setup\n"), 0644)
+
+	// the tree of a single file upload only holds the file and its ancestors
+	pt := NewPageTree(LoadYamlResourceChain(filepath.Join(spaceDir, "apps", "app1.yml")), "")
+	rt := &RenderTools{dirProps: utils.DirectoryProperties{SpaceDir: spaceDir}, pt: pt}
+	links := &linkRenderer{rt: rt, page: pt.GetPage("/apps/app1.yml"), representation: WIKI_REPRESENTATION}
+
+	markup, err := renderTemplate("{{#y2c_link}}app2.yml{{/y2c_link}} {{#y2c_link}}../guides/setup.yml{{/y2c_link}} {{#y2c_link}}_index.yml{{/y2c_link}}", links)
+	if err != nil || markup != "[App 2] [Setup] [Applications]" {
+		t.Errorf("Expected links to pages outside of the tree to be resolved by their files, got %s %v", markup, err)
+	}
+}
+
+func TestRenderTemplateLinks(t *testing.T) {
+	pt := NewPageTree([]*YamlResource{
+		NewYamlResource("/apps", createYamlNode("wiki", "Applications")),
+		NewYamlResource("/apps/app1.yml", createYamlNode("wiki", "App & 1")),
+		NewYamlResource("/freeform.yml", createYamlNode("wiki", "Freeform")),
+	}, "")
+	rt := &RenderTools{dirProps: utils.DirectoryProperties{SpaceDir: t.TempDir()}, pt: pt}
+	links := &linkRenderer{rt: rt, page: pt.GetPage("/freeform.yml"), representation: WIKI_REPRESENTATION}
+
+	obj := map[string]interface{}{
+		"link": map[string]interface{}{"url": "https://example.com"},
+		"apps": []interface{}{map[string]interface{}{"path": "apps/app1.yml", "name": "first & only"}},
+	}
+	markup, err := renderTemplate("{{#y2c_link}}/apps{{/y2c_link}} {{#apps}}{{#y2c_link}}{{path}}|{{name}}{{/y2c_link}}{{/apps}}", links, obj)
+	if err != nil || markup != "[Applications] [first & only|App & 1]" {
+		t.Errorf("Expected the links to be rendered in the context of their section, got %s %v", markup, err)
+	}
+
+	// link is a field like any other
+	if markup, err := renderTemplate("{{#link}}{{url}}{{/link}}{{^link}}none{{/link}}", links, obj); err != nil || markup != "https://example.com" {
+		t.Errorf("Expected the link field of the resource to be rendered, got %s %v", markup, err)
+	}
+
+	if _, err := renderTemplate("{{#y2c_link}}missing.yml{{/y2c_link}}", links, obj); err == nil {
+		t.Errorf("Expected a link to a missing resource to fail the rendering")
+	}
+}
+
+func TestFormatLink(t *testing.T) {
+	tests := []struct {
+		representation string
+		spaceKey       string
+		alias          string
+		expected       string
+	}{
+		{WIKI_REPRESENTATION, "", "", "[App & 1]"},
+		{WIKI_REPRESENTATION, "OTHER", "the app", "[the app|OTHER:App & 1]"},
+		{STORAGE_REPRESENTATION, "", "", `<ac:link><ri:page ri:content-title="App &amp; 1" /></ac:link>`},
+		{MARKDOWN_REPRESENTATION, "OTHER", "the app", `<ac:link><ri:page ri:content-title="App &amp; 1" ri:space-key="OTHER" /><ac:plain-text-link-body><![CDATA[the app]]></ac:plain-text-link-body></ac:link>`},
+	}
+	for _, test := range tests {
+		link, err := formatLink(test.representation, test.spaceKey, "App & 1", test.alias)
+		if err != nil || link != test.expected {
+			t.Errorf("Expected %s, got %s %v", test.expected, link, err)
+		}
+	}
+
+	// markdown links are only storage format once the page is converted
+	link, _ := formatLink(MARKDOWN_REPRESENTATION, "", "App & 1", "the *app*")
+	if storage, err := MarkdownToStorage("See " + link + "\n"); err != nil || storage != "<p>See "+link+"</p>\n" {
+		t.Errorf("Expected the link to be kept by the markdown conversion, got %s %v", storage, err)
+	}
+
+	if _, err := formatLink(ADF_REPRESENTATION, "", "App", ""); err == nil {
+		t.Errorf("Expected links to be rejected by %s", ADF_REPRESENTATION)
+	}
+}
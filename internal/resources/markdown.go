@@ -0,0 +1,229 @@
+package resources
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer"
+	goldhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(
+		// storage format is XHTML
+		goldhtml.WithXHTML(),
+		// raw HTML is kept, storage format elements are passed through as placeholders by MarkdownToStorage
+		goldhtml.WithUnsafe(),
+		renderer.WithNodeRenderers(util.Prioritized(&storageRenderer{}, 100)),
+	),
+)
+
+// the tags of storage format elements (ac:, ri:) aren't HTML tags for CommonMark and CDATA isn't kept by every
+// parser, they are replaced by HTML tags before the conversion and restored after it
+var storageMarkup = regexp.MustCompile(`(?s)<!\[CDATA\[.*?\]\]>|</?(?:ac|ri):([A-Za-z][\w-]*)(?:"[^"]*"|'[^']*'|[^'"<>])*>`)
+var storagePlaceholder = regexp.MustCompile(`(<|&lt;)(?:div|span) y2c-storage=(?:"|&quot;)(\d+)(?:"|&quot;) /(?:>|&gt;)`)
+
+// storage format elements starting a line start an HTML block like a div, others are inline like a span
+var STORAGE_BLOCK_ELEMENTS = map[string]bool{
+	"structured-macro": true,
+	"rich-text-body":   true,
+	"plain-text-body":  true,
+	"layout":           true,
+	"layout-section":   true,
+	"layout-cell":      true,
+	"task-list":        true,
+	"task":             true,
+}
+
+// MarkdownToStorage converts CommonMark with GitHub Flavored Markdown extensions to Confluence storage format,
+// storage format elements in the markdown are kept
+func MarkdownToStorage(source string) (string, error) {
+	elements := []string{}
+	source = storageMarkup.ReplaceAllStringFunc(source, func(element string) string {
+		elements = append(elements, element)
+		tag := "span"
+		if STORAGE_BLOCK_ELEMENTS[storageMarkup.FindStringSubmatch(element)[1]] {
+			tag = "div"
+		}
+		return fmt.Sprintf(`<%s y2c-storage="%d" />`, tag, len(elements)-1)
+	})
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+
+	return storagePlaceholder.ReplaceAllStringFunc(buf.String(), func(placeholder string) string {
+		match := storagePlaceholder.FindStringSubmatch(placeholder)
+		index, _ := strconv.Atoi(match[2])
+		// placeholders in code are escaped, so is the element
+		if match[1] == "&lt;" {
+			return html.EscapeString(elements[index])
+		}
+		return elements[index]
+	}), nil
+}
+
+// storageRenderer replaces the HTML of elements that have a Confluence specific representation
+type storageRenderer struct{}
+
+func (r *storageRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderCodeBlock)
+	reg.Register(ast.KindCodeBlock, r.renderCodeBlock)
+	reg.Register(ast.KindList, r.renderList)
+	reg.Register(ast.KindListItem, r.renderListItem)
+	reg.Register(extast.KindTaskCheckBox, r.renderTaskCheckBox)
+	reg.Register(ast.KindImage, r.renderImage)
+}
+
+func (r *storageRenderer) renderCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	w.WriteString(`<ac:structured-macro ac:name="code">`)
+	if fenced, ok := node.(*ast.FencedCodeBlock); ok {
+		if language := fenced.Language(source); language != nil {
+			fmt.Fprintf(w, `<ac:parameter ac:name="language">%s</ac:parameter>`, html.EscapeString(string(language)))
+		}
+	}
+
+	var code strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	// "]]>" can't appear inside of CDATA, it is split over two sections
+	w.WriteString("<ac:plain-text-body><![CDATA[")
+	w.WriteString(strings.ReplaceAll(strings.TrimSuffix(code.String(), "\n"), "]]>", "]]]]><![CDATA[>"))
+	w.WriteString("]]></ac:plain-text-body></ac:structured-macro>\n")
+
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *storageRenderer) renderList(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.List)
+
+	if isTaskList(n) {
+		if entering {
+			w.WriteString("<ac:task-list>\n")
+		} else {
+			w.WriteString("</ac:task-list>\n")
+		}
+		return ast.WalkContinue, nil
+	}
+
+	tag := "ul"
+	if n.IsOrdered() {
+		tag = "ol"
+	}
+	if entering {
+		if n.IsOrdered() && n.Start != 1 {
+			fmt.Fprintf(w, "<%s start=\"%d\">\n", tag, n.Start)
+		} else {
+			fmt.Fprintf(w, "<%s>\n", tag)
+		}
+	} else {
+		fmt.Fprintf(w, "</%s>\n", tag)
+	}
+
+	return ast.WalkContinue, nil
+}
+
+func (r *storageRenderer) renderListItem(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if isTaskList(node.Parent().(*ast.List)) {
+		if entering {
+			status := "incomplete"
+			if getTaskCheckBox(node).IsChecked {
+				status = "complete"
+			}
+			fmt.Fprintf(w, "<ac:task><ac:task-status>%s</ac:task-status><ac:task-body>", status)
+		} else {
+			w.WriteString("</ac:task-body></ac:task>\n")
+		}
+		return ast.WalkContinue, nil
+	}
+
+	if entering {
+		w.WriteString("<li>")
+		if _, ok := node.FirstChild().(*ast.TextBlock); node.FirstChild() != nil && !ok {
+			w.WriteByte('\n')
+		}
+	} else {
+		w.WriteString("</li>\n")
+	}
+
+	return ast.WalkContinue, nil
+}
+
+// check boxes are only rendered in lists that mix tasks with regular items
+func (r *storageRenderer) renderTaskCheckBox(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering || isTaskList(node.Parent().Parent().Parent().(*ast.List)) {
+		return ast.WalkContinue, nil
+	}
+
+	if node.(*extast.TaskCheckBox).IsChecked {
+		w.WriteString("[x] ")
+	} else {
+		w.WriteString("[ ] ")
+	}
+
+	return ast.WalkContinue, nil
+}
+
+func (r *storageRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*ast.Image)
+	w.WriteString("<ac:image")
+	if alt := n.Text(source); len(alt) > 0 {
+		fmt.Fprintf(w, ` ac:alt="%s"`, html.EscapeString(string(alt)))
+	}
+	if len(n.Title) > 0 {
+		fmt.Fprintf(w, ` ac:title="%s"`, html.EscapeString(string(n.Title)))
+	}
+	destination := string(n.Destination)
+	if strings.Contains(destination, "://") {
+		fmt.Fprintf(w, `><ri:url ri:value="%s" /></ac:image>`, html.EscapeString(destination))
+	} else {
+		// relative images refer to attachments of the page
+		fmt.Fprintf(w, `><ri:attachment ri:filename="%s" /></ac:image>`, html.EscapeString(path.Base(destination)))
+	}
+
+	return ast.WalkSkipChildren, nil
+}
+
+// isTaskList is true when every item of the list starts with a check box
+func isTaskList(list *ast.List) bool {
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		if getTaskCheckBox(item) == nil {
+			return false
+		}
+	}
+
+	return list.HasChildren()
+}
+
+func getTaskCheckBox(item ast.Node) *extast.TaskCheckBox {
+	if item.FirstChild() == nil {
+		return nil
+	}
+
+	checkBox, _ := item.FirstChild().FirstChild().(*extast.TaskCheckBox)
+	return checkBox
+}
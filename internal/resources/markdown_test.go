@@ -0,0 +1,92 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToStorageCodeFence(t *testing.T) {
+	storage, err := MarkdownToStorage("```go\nfmt.Println(\"]]>\")\n```\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">go</ac:parameter><ac:plain-text-body><![CDATA[fmt.Println("]]]]><![CDATA[>")]]></ac:plain-text-body></ac:structured-macro>` + "\n"
+	if storage != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, storage)
+	}
+}
+
+func TestMarkdownToStorageTaskList(t *testing.T) {
+	storage, err := MarkdownToStorage("- [x] done\n- [ ] todo\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "<ac:task-list>\n" +
+		"<ac:task><ac:task-status>complete</ac:task-status><ac:task-body>done</ac:task-body></ac:task>\n" +
+		"<ac:task><ac:task-status>incomplete</ac:task-status><ac:task-body>todo</ac:task-body></ac:task>\n" +
+		"</ac:task-list>\n"
+	if storage != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, storage)
+	}
+}
+
+func TestMarkdownToStorageXhtml(t *testing.T) {
+	storage, err := MarkdownToStorage("| a | b |\n|---|---|\n| 1 | 2 |\n\nline  \nbreak\n\n---\n\n- [ ] mixed\n- item\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"<table>", "<td>1</td>", "<br />", "<hr />", "<li>[ ] mixed</li>"} {
+		if !strings.Contains(storage, expected) {
+			t.Errorf("Expected %s in\n%s", expected, storage)
+		}
+	}
+}
+
+func TestUnmarshalMarkdown(t *testing.T) {
+	yr := NewYamlResource("/page.md", unmarshalMarkdown("/page.md", []byte("---\ntitle: Page\nlabels: [docs]\n---\n# Heading\n")))
+
+	if yr.Kind != "markdown" || yr.Title != "Page" {
+		t.Errorf("Expected kind markdown and title Page, got %s and %s", yr.Kind, yr.Title)
+	}
+	if markdown := yr.ToObject()["markdown"]; markdown != "# Heading\n" {
+		t.Errorf("Expected the markdown field to hold the body, got %q", markdown)
+	}
+	if labels := yr.GetLabels(); len(labels) != 1 || labels[0] != "docs" {
+		t.Errorf("Expected front matter labels, got %v", labels)
+	}
+}
+
+func TestUnmarshalMarkdownWithoutFrontMatter(t *testing.T) {
+	yr := NewYamlResource("/docs/my-page.md", unmarshalMarkdown("/docs/my-page.md", []byte("---\n\ntext\n")))
+
+	if yr.Kind != "markdown" || yr.Title != "my-page" {
+		t.Errorf("Expected kind markdown and title my-page, got %s and %s", yr.Kind, yr.Title)
+	}
+	if markdown := yr.ToObject()["markdown"]; markdown != "---\n\ntext\n" {
+		t.Errorf("Expected the whole file as markdown, got %q", markdown)
+	}
+}
+
+func TestMarkdownToStorageKeepsStorageFormat(t *testing.T) {
+	link := `<ac:link><ri:page ri:content-title="Getting started" /><ac:plain-text-link-body><![CDATA[the *first* steps]]></ac:plain-text-link-body></ac:link>`
+	macro := "<ac:structured-macro ac:name=\"info\"><ac:rich-text-body>\n<p>Note</p>\n</ac:rich-text-body></ac:structured-macro>"
+
+	storage, err := MarkdownToStorage("Read " + link + " first.\n\n" + macro + "\n\nNot a link: `<ac:link>`\n\n    <ri:page />\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"<p>Read " + link + " first.</p>",
+		macro,
+		"<code>&lt;ac:link&gt;</code>",
+		"<![CDATA[<ri:page />]]>",
+	} {
+		if !strings.Contains(storage, expected) {
+			t.Errorf("Expected %s in\n%s", expected, storage)
+		}
+	}
+}
@@ -1,33 +1,67 @@
 package resources
 
 import (
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
 )
 
 type ChangeType int
 
+// the content property of a page holding the page version written by the last upload
+const PUBLISHED_VERSION_PROPERTY = "published_version"
+
+// the content property of a page holding the identity of its resource, see YamlResource.GetResourceId
+const RESOURCE_ID_PROPERTY = "resource_id"
+
+// the content property of a page holding its position, the resource id of its preceding sibling
+const POSITION_PROPERTY = "position"
+
+// POSITION_FIRST is the position of the first of the ordered siblings
+const POSITION_FIRST = "/"
+
 const (
 	CREATE ChangeType = iota
 	UPDATE
 	DELETE
 	NOOP
+	// MOVE gives an existing page another parent, its title and content are updated as well
+	MOVE
+	// RENAME changes the title of an existing page, its content is updated as well
+	RENAME
+	// ORDER moves the children of a page into the order declared by their resources, the page itself is unchanged
+	ORDER
 )
 
+// PagePosition moves a page before or after one of its siblings
+type PagePosition struct {
+	Page *Page
+	// Position is before or after
+	Position string
+	Target   *Page
+	// Previous is the sibling preceding the page once it is moved, nil for the first one
+	Previous *Page
+}
+
 type Page struct {
 	Key      string
 	Resource *YamlResource
 	Content  PageContent
-	Remote   *RemoteResource
-	Parent   *Page
+	// Attachments are the local files declared by the resource, resolved when it is rendered
+	Attachments []LocalAttachment
+	Remote      *RemoteResource
+	Parent      *Page
 	// childrenByTitle map[string]*Page
 	Children []*Page
 }
 
 type PageContent struct {
-	Markup string
-	Sha256 string
+	Markup         string
+	Representation string
+	Sha256         string
 }
 
 func NewPage(key string, yr *YamlResource) *Page {
@@ -138,8 +172,42 @@ func (p *Page) Sha256Differs() bool {
 
 	return false
 }
+
+// IsMoved reports whether the page has another parent in Confluence than the parent of its resource
+func (p *Page) IsMoved() bool {
+	if p.IsRoot() || p.Resource == nil || p.Remote == nil || p.Remote.GetParentId() == "" {
+		return false
+	}
+
+	return p.Remote.GetParentId() != p.GetAncestorId()
+}
+
+// IsRenamed reports whether the title of the page in Confluence differs from the title of its resource
+func (p *Page) IsRenamed() bool {
+	if p.Resource == nil || p.Remote == nil {
+		return false
+	}
+
+	return p.Remote.Title != p.GetTitle()
+}
+
+// ResourceIdDiffers reports whether the page doesn't record the identity of its resource yet
+func (p *Page) ResourceIdDiffers() bool {
+	if p.Resource == nil || p.Remote == nil {
+		return false
+	}
+
+	return p.Resource.GetResourceId() != p.Remote.ResourceId.Value
+}
+
 func (p *Page) GetChangeType() ChangeType {
-	if p.Sha256Differs() || p.LabelsDiffer() {
+	if p.IsMoved() {
+		return MOVE
+	}
+	if p.IsRenamed() {
+		return RENAME
+	}
+	if p.Sha256Differs() || p.LabelsDiffer() || p.AttachmentsDiffer() {
 		return UPDATE
 	}
 	if p.Resource != nil && p.Remote == nil {
@@ -152,8 +220,155 @@ func (p *Page) GetChangeType() ChangeType {
 	return NOOP
 }
 
+// GetOrderedChildren returns the children of the page in the order declared by their resources. The children listed
+// by the childOrder field of the page come first, then the children with an order ordered by it, then the others.
+// Ties are ordered by title.
+func (p *Page) GetOrderedChildren() []*Page {
+	listed := map[string]int{}
+	if p.Resource != nil {
+		for i, name := range p.Resource.GetChildOrder() {
+			listed[name] = i
+		}
+	}
+
+	rank := func(child *Page) (int, int) {
+		name := filepath.Base(child.Key)
+		if i, exists := listed[name]; exists {
+			return 0, i
+		}
+		if i, exists := listed[strings.TrimSuffix(name, filepath.Ext(name))]; exists {
+			return 0, i
+		}
+		if order, exists := child.Resource.GetOrder(); exists {
+			return 1, order
+		}
+
+		return 2, 0
+	}
+
+	children := append([]*Page{}, p.Children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		groupI, orderI := rank(children[i])
+		groupJ, orderJ := rank(children[j])
+		if groupI != groupJ {
+			return groupI < groupJ
+		}
+		if orderI != orderJ {
+			return orderI < orderJ
+		}
+
+		return strings.ToLower(children[i].GetTitle()) < strings.ToLower(children[j].GetTitle())
+	})
+
+	return children
+}
+
+// IsOrdered reports whether the order of the children of the page is declared, otherwise it is left to Confluence
+func (p *Page) IsOrdered() bool {
+	if p.Resource != nil && len(p.Resource.GetChildOrder()) > 0 {
+		return true
+	}
+	for _, child := range p.Children {
+		if _, exists := child.Resource.GetOrder(); exists {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetPositionChanges returns the moves putting the children of the page in order. The children keep their place
+// up to the first one whose recorded position differs, that one and all following ones are moved.
+func (p *Page) GetPositionChanges() []PagePosition {
+	positions := []PagePosition{}
+	if !p.IsOrdered() {
+		return positions
+	}
+
+	children := p.GetOrderedChildren()
+	for i, child := range children {
+		var previous *Page
+		if i > 0 {
+			previous = children[i-1]
+		}
+		if len(positions) == 0 && !child.positionDiffers(previous) {
+			continue
+		}
+
+		if previous == nil {
+			if len(children) > 1 {
+				positions = append(positions, PagePosition{Page: child, Position: "before", Target: children[1]})
+			} else {
+				positions = append(positions, PagePosition{Page: child})
+			}
+		} else {
+			positions = append(positions, PagePosition{Page: child, Position: "after", Target: previous, Previous: previous})
+		}
+	}
+
+	return positions
+}
+
+// positionDiffers reports whether the page wasn't ordered after previous by the last upload, new and moved pages
+// always differ
+func (p *Page) positionDiffers(previous *Page) bool {
+	if p.Remote == nil || p.Remote.Position.Id == "" || p.IsMoved() {
+		return true
+	}
+
+	return p.Remote.Position.Value != getPosition(previous)
+}
+
+func getPosition(previous *Page) string {
+	if previous == nil {
+		return POSITION_FIRST
+	}
+
+	return previous.Resource.GetResourceId()
+}
+
 func (p *Page) GetSha256Property() Property {
-	return NewProperty(p.GetRemoteId(), "sha256", p.Content.Sha256, p.GetRemoteSha256Version())
+	propertyId := ""
+	if p.Remote != nil {
+		propertyId = p.Remote.Sha256.Id
+	}
+
+	return NewProperty(p.GetRemoteId(), propertyId, "sha256", p.Content.Sha256, p.GetRemoteSha256Version())
+}
+
+// GetPublishedVersionProperty records version as the version of the page written by y2c
+func (p *Page) GetPublishedVersionProperty(version int) Property {
+	propertyId := ""
+	propertyVersion := 0
+	if p.Remote != nil {
+		propertyId = p.Remote.PublishedVersion.Id
+		propertyVersion = p.Remote.PublishedVersion.Version
+	}
+
+	return NewProperty(p.GetRemoteId(), propertyId, PUBLISHED_VERSION_PROPERTY, strconv.Itoa(version), propertyVersion)
+}
+
+func (p *Page) GetResourceIdProperty() Property {
+	propertyId := ""
+	propertyVersion := 0
+	if p.Remote != nil {
+		propertyId = p.Remote.ResourceId.Id
+		propertyVersion = p.Remote.ResourceId.Version
+	}
+
+	return NewProperty(p.GetRemoteId(), propertyId, RESOURCE_ID_PROPERTY, p.Resource.GetResourceId(), propertyVersion)
+}
+
+// GetPositionProperty records previous as the sibling preceding the page
+func (p *Page) GetPositionProperty(previous *Page) Property {
+	propertyId := ""
+	propertyVersion := 0
+	if p.Remote != nil {
+		propertyId = p.Remote.Position.Id
+		propertyVersion = p.Remote.Position.Version
+	}
+
+	return NewProperty(p.GetRemoteId(), propertyId, POSITION_PROPERTY, getPosition(previous), propertyVersion)
 }
 
 // -------------------------
@@ -164,20 +379,43 @@ func (p *Page) GetId() string {
 	return p.GetRemoteId()
 }
 func (p *Page) GetTitle() string {
+	// a homepage without title keeps the one it has in Confluence
+	if p.Resource.Title == "" && p.IsRoot() && p.Remote != nil {
+		return p.Remote.Title
+	}
+
 	return p.Resource.Title
 }
 func (p *Page) GetAncestorId() string {
+	// the homepage stays where it is
+	if p.IsRoot() {
+		return ""
+	}
+
 	return p.GetParent().GetRemoteId()
 }
 func (p *Page) GetContent() string {
 	return p.Content.Markup
 }
+func (p *Page) GetRepresentation() string {
+	if p.Content.Representation == "" {
+		return WIKI_REPRESENTATION
+	}
+
+	return p.Content.Representation
+}
+func (p *Page) GetEditorVersion() string {
+	return p.Resource.GetEditorVersion()
+}
 func (p *Page) GetLabels() []string {
 	return p.Resource.GetLabels()
 }
 func (p *Page) GetIncrementedVersion() int {
 	return p.GetRemoteVersion() + 1
 }
+
+// IsUpdate reports whether the page already exists in Confluence, it is updated in place whatever the planned
+// change, e.g. a drifted page overwritten by the drift policy is planned as a NOOP
 func (p *Page) IsUpdate() bool {
-	return p.GetChangeType() == UPDATE
+	return p.Remote != nil
 }
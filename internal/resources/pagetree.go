@@ -2,6 +2,7 @@ package resources
 
 import (
 	"sort"
+	"strings"
 )
 
 type PageTree struct {
@@ -15,6 +16,16 @@ type PageUpdate struct {
 	Page      *Page
 }
 
+// IsLabelsOnly reports whether an UPDATE leaves the page content untouched and only changes its labels
+func (pu PageUpdate) IsLabelsOnly() bool {
+	return pu.Operation == UPDATE && !pu.Page.Sha256Differs() && pu.Page.LabelsDiffer()
+}
+
+// IsAttachmentsOnly reports whether an UPDATE only uploads or deletes attachments, the page itself is left untouched
+func (pu PageUpdate) IsAttachmentsOnly() bool {
+	return pu.Operation == UPDATE && !pu.Page.Sha256Differs() && !pu.Page.LabelsDiffer() && pu.Page.AttachmentsDiffer()
+}
+
 func NewPageTree(yr []*YamlResource, anchor string) *PageTree {
 	pageTree := &PageTree{}
 
@@ -52,22 +63,79 @@ func (pt *PageTree) HasAnchor() bool {
 	return pt.GetAnchor() != ""
 }
 
+// SetHomepage renders yr into the page the tree is anchored to, the homepage of the space unless it has an anchor
+func (pt *PageTree) SetHomepage(yr *YamlResource) {
+	pt.rootPage.Resource = yr
+}
+
+// GetHomepage returns the root page when a resource is rendered into it, nil otherwise
+func (pt *PageTree) GetHomepage() *Page {
+	if pt.rootPage.Resource == nil {
+		return nil
+	}
+
+	return pt.rootPage
+}
+
+// SetHomepageRemote attaches the homepage retrieved from Confluence to the root page
+func (pt *PageTree) SetHomepageRemote(remote *RemoteResource) {
+	pt.rootPage.Remote = remote
+}
+
 type orphanPage struct {
 	depth  int
 	remote *RemoteResource
 }
 
+// AddRemotes attaches the managed pages to the pages of their resources. A remote is matched by the resource id it
+// was published from, then by its title path and finally by its title alone, titles being unique in a space. Only
+// remotes published before resource ids were recorded are matched by title, when no other remote has the title.
+// The remotes without page are deleted.
 func (pt *PageTree) AddRemotes(remotes []*RemoteResource) {
 	orphans := []orphanPage{}
 
+	byResourceId := map[string]*Page{}
+	byTitle := map[string]*Page{}
+	for _, page := range pt.GetPages() {
+		byResourceId[page.Resource.GetResourceId()] = page
+		byTitle[page.GetTitle()] = page
+	}
+
+	unmatched := []*RemoteResource{}
+	for _, remote := range remotes {
+		// a homepage published by y2c is managed, but it is the root of the tree
+		if remote.Id == pt.GetAnchor() {
+			continue
+		}
+		if page := byResourceId[remote.ResourceId.Value]; page != nil && remote.ResourceId.Value != "" && page.Remote == nil {
+			page.Remote = remote
+		} else {
+			unmatched = append(unmatched, remote)
+		}
+	}
+
+	remotes, unmatched = unmatched, []*RemoteResource{}
 	for _, remote := range remotes {
-		titlePath := remote.GetTitlePath(pt.GetAnchor())
-		page := pt.GetPageFromTitlePath(titlePath)
-		if page != nil {
+		if page := pt.GetPageFromTitlePath(remote.GetTitlePath(pt.GetAnchor())); page != nil && page.Remote == nil {
+			page.Remote = remote
+		} else {
+			unmatched = append(unmatched, remote)
+		}
+	}
+
+	titles := map[string]int{}
+	for _, remote := range unmatched {
+		titles[remote.Title]++
+	}
+
+	// a remote with a resource id was published from a resource that no longer exists, it is deleted even when a
+	// new resource has its title
+	for _, remote := range unmatched {
+		if page := byTitle[remote.Title]; page != nil && page.Remote == nil && remote.ResourceId.Value == "" && titles[remote.Title] == 1 {
 			page.Remote = remote
 		} else {
 			orphans = append(orphans, orphanPage{
-				depth:  len(titlePath),
+				depth:  len(remote.GetTitlePath(pt.GetAnchor())),
 				remote: remote,
 			})
 		}
@@ -101,6 +169,36 @@ func (pt *PageTree) setDeletes(orphans []orphanPage) {
 	pt.deletes = deletes
 }
 
+// GetDeletes returns the pages that only exist in Confluence and are deleted by GetChanges
+func (pt *PageTree) GetDeletes() []*Page {
+	pages := []*Page{}
+	for _, group := range pt.deletes {
+		for _, pu := range group {
+			pages = append(pages, pu.Page)
+		}
+	}
+
+	return pages
+}
+
+// KeepDeletes removes the pages with the given remote ids from the pages to delete
+func (pt *PageTree) KeepDeletes(ids map[string]bool) {
+	deletes := [][]PageUpdate{}
+	for _, group := range pt.deletes {
+		kept := []PageUpdate{}
+		for _, pu := range group {
+			if !ids[pu.Page.GetRemoteId()] {
+				kept = append(kept, pu)
+			}
+		}
+		if len(kept) > 0 {
+			deletes = append(deletes, kept)
+		}
+	}
+
+	pt.deletes = deletes
+}
+
 func (pt *PageTree) AddPage(yr *YamlResource) {
 	page := NewPage(yr.Path, yr)
 	pt.pages[yr.GetParentPath()].AppendChild(page)
@@ -111,6 +209,27 @@ func (pt *PageTree) GetPage(key string) *Page {
 	return pt.pages[key]
 }
 
+// GetPagePath returns the local resource path of a page, or the remote title path for pages that only exist in Confluence
+func (pt *PageTree) GetPagePath(p *Page) string {
+	if p.Resource != nil {
+		return p.Resource.Path
+	}
+	if p == pt.rootPage {
+		return "/"
+	}
+
+	return pt.GetRemotePath(p)
+}
+
+// GetRemotePath returns the title path of a page in Confluence, it differs from the resource path of moved pages
+func (pt *PageTree) GetRemotePath(p *Page) string {
+	if p.Remote == nil {
+		return ""
+	}
+
+	return "/" + strings.Join(p.Remote.GetTitlePath(pt.GetAnchor()), "/")
+}
+
 func (pt *PageTree) GetPageFromTitlePath(titles []string) *Page {
 	page := pt.rootPage
 	for _, title := range titles {
@@ -158,16 +277,26 @@ func (pt *PageTree) GetChanges() [][]PageUpdate {
 	updates := []PageUpdate{}
 	skips := []PageUpdate{}
 
+	if homepage := pt.GetHomepage(); homepage != nil {
+		pu := createHomepageUpdate(homepage)
+		if pu.Operation == NOOP {
+			skips = append(skips, pu)
+		} else {
+			updates = append(updates, pu)
+		}
+	}
+
 	level := pt.rootPage.GetChildren()
 
 	for len(level) > 0 {
+		// moved pages may need a parent created in an earlier wave, renames wait for deletes that free their title
 		creates := []PageUpdate{}
 
 		children := []*Page{}
 		for _, page := range level {
 			pu := createPageUpdate(page)
 			switch pu.Operation {
-			case CREATE:
+			case CREATE, MOVE, RENAME:
 				creates = append(creates, pu)
 			case UPDATE:
 				updates = append(updates, pu)
@@ -185,6 +314,11 @@ func (pt *PageTree) GetChanges() [][]PageUpdate {
 		level = children
 	}
 
+	// pages are ordered once all of them exist where they belong
+	if orders := pt.getOrderChanges(); len(orders) > 0 {
+		changes = append(changes, orders)
+	}
+
 	// all updates can be applied in the first grouping
 	changes = append(mergePageUpdates(pt.deletes, [][]PageUpdate{updates}), changes...)
 	changes = append(changes, skips)
@@ -192,6 +326,50 @@ func (pt *PageTree) GetChanges() [][]PageUpdate {
 	return changes
 }
 
+// getOrderChanges returns an ORDER change for every page whose children are not in their declared order, sorted by key
+func (pt *PageTree) getOrderChanges() []PageUpdate {
+	orders := []PageUpdate{}
+	for _, page := range pt.pages {
+		if len(page.GetPositionChanges()) > 0 {
+			orders = append(orders, PageUpdate{Operation: ORDER, Page: page})
+		}
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].Page.Key < orders[j].Page.Key
+	})
+
+	return orders
+}
+
+// GetChangesFor returns the changes needed to publish a single page. Ancestors missing from Confluence are
+// created first, existing ancestors are left untouched and nothing is ever deleted.
+func (pt *PageTree) GetChangesFor(key string) [][]PageUpdate {
+	changes := [][]PageUpdate{}
+
+	if homepage := pt.GetHomepage(); homepage != nil && homepage.Resource.Path == key {
+		return append(changes, []PageUpdate{createHomepageUpdate(homepage)})
+	}
+
+	page := pt.pages[key]
+	if page == nil || page.IsRoot() {
+		return changes
+	}
+
+	ancestors := []*Page{}
+	for parent := page.GetParent(); !parent.IsRoot(); parent = parent.GetParent() {
+		ancestors = append([]*Page{parent}, ancestors...)
+	}
+
+	for _, ancestor := range ancestors {
+		if pu := createPageUpdate(ancestor); pu.Operation == CREATE {
+			changes = append(changes, []PageUpdate{pu})
+		}
+	}
+
+	return append(changes, []PageUpdate{createPageUpdate(page)})
+}
+
 func mergePageUpdates(p1, p2 [][]PageUpdate) [][]PageUpdate {
 	m := [][]PageUpdate{}
 
@@ -234,6 +412,16 @@ func createPageUpdate(p *Page) PageUpdate {
 		Page:      p,
 	}
 }
+
+// createHomepageUpdate updates the homepage or anchor page, it always exists and keeps its place when its title changes
+func createHomepageUpdate(p *Page) PageUpdate {
+	pu := createPageUpdate(p)
+	if pu.Operation != NOOP {
+		pu.Operation = UPDATE
+	}
+
+	return pu
+}
 func createDeletePageUpdate(remote *RemoteResource) PageUpdate {
 	return PageUpdate{
 		Operation: DELETE,
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
+	"gopkg.in/yaml.v3"
 )
 
 func validateLevels(expected, actual [][]string, t *testing.T) {
@@ -127,3 +130,189 @@ func TestMergePageUpdates(t *testing.T) {
 	}
 
 }
+
+func TestGetChangesFor(t *testing.T) {
+	yr := []*YamlResource{
+		NewYamlResource("/apps", createYamlNode("wiki", "apps root")),
+		NewYamlResource("/apps/app1.yml", createYamlNode("wiki", "test app 1")),
+		NewYamlResource("/apps/nested", createYamlNode("wiki", "nested apps root")),
+		NewYamlResource("/apps/nested/app2.yml", createYamlNode("wiki", "test app 2")),
+	}
+
+	pt := NewPageTree(yr, "1")
+	pt.GetPage("/apps").Remote = &RemoteResource{Id: "2", Title: "apps root", Labels: []string{constants.GENERATED_BY_LABEL}}
+	pt.GetPage("/apps/app1.yml").Remote = &RemoteResource{Id: "3", Title: "test app 1", Labels: []string{constants.GENERATED_BY_LABEL}}
+
+	changes := pt.GetChangesFor("/apps/nested/app2.yml")
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 groups of changes, got %d", len(changes))
+	}
+	if changes[0][0].Page.Key != "/apps/nested" || changes[0][0].Operation != CREATE {
+		t.Fatalf(`Expected missing ancestor "/apps/nested" to be created first, got "%s"`, changes[0][0].Page.Key)
+	}
+	if changes[1][0].Page.Key != "/apps/nested/app2.yml" || changes[1][0].Operation != CREATE {
+		t.Fatalf(`Expected "/apps/nested/app2.yml" to be created last, got "%s"`, changes[1][0].Page.Key)
+	}
+
+	changes = pt.GetChangesFor("/apps/app1.yml")
+	if len(changes) != 1 || changes[0][0].Operation != NOOP {
+		t.Fatalf("Expected a single NOOP change for an existing page, got %v", changes)
+	}
+}
+
+func TestKeepDeletes(t *testing.T) {
+	pt := NewPageTree([]*YamlResource{}, "1")
+	pt.AddRemotes([]*RemoteResource{
+		{Id: "2", Title: "parent", Ancestors: []Ancestor{{"0", "space"}, {"1", "anchor"}}},
+		{Id: "3", Title: "child", Ancestors: []Ancestor{{"0", "space"}, {"1", "anchor"}, {"2", "parent"}}},
+		{Id: "4", Title: "other child", Ancestors: []Ancestor{{"0", "space"}, {"1", "anchor"}, {"2", "parent"}}},
+	})
+
+	if len(pt.GetDeletes()) != 3 {
+		t.Fatalf("Expected 3 deletes, got %d", len(pt.GetDeletes()))
+	}
+
+	pt.KeepDeletes(map[string]bool{"2": true, "4": true})
+	if deletes := pageUpdateToString(pt.deletes); deletes != "3" {
+		t.Fatalf(`Expected only "3" to be deleted, got "%s"`, deletes)
+	}
+}
+
+func TestIsEditedManually(t *testing.T) {
+	published := &RemoteResource{Version: 3, PublishedVersion: RemotePublishedVersion{Value: 2}, LastModifier: "y2c"}
+	if !published.IsEditedManually("y2c") {
+		t.Errorf("Expected a version newer than the published version to be a manual edit")
+	}
+	published.Version = 2
+	if published.IsEditedManually("someone") {
+		t.Errorf("Expected the published version to take precedence over the last modifier")
+	}
+
+	unrecorded := &RemoteResource{Version: 5, LastModifier: "someone"}
+	if !unrecorded.IsEditedManually("y2c") || unrecorded.IsEditedManually("someone") {
+		t.Errorf("Expected the last modifier to be compared when no version was recorded")
+	}
+}
+
+func TestAddRemotesMovesAndRenames(t *testing.T) {
+	yr := []*YamlResource{
+		NewYamlResource("/apps", createYamlNode("wiki", "apps root")),
+		NewYamlResource("/apps/app1.yml", createYamlNode("wiki", "renamed app 1")),
+		NewYamlResource("/docs", createYamlNode("wiki", "docs root")),
+		NewYamlResource("/docs/app2.yml", createYamlNode("wiki", "test app 2")),
+	}
+
+	pt := NewPageTree(yr, "1")
+	ancestors := []Ancestor{{"0", "space"}, {"1", "anchor"}}
+	pt.AddRemotes([]*RemoteResource{
+		{Id: "2", Title: "apps root", Ancestors: ancestors, ResourceId: RemoteResourceId{Value: "/apps"}},
+		{Id: "3", Title: "test app 1", Ancestors: append(ancestors, Ancestor{"2", "apps root"}), ResourceId: RemoteResourceId{Value: "/apps/app1.yml"}},
+		// published before resource ids were recorded
+		{Id: "4", Title: "test app 2", Ancestors: append(ancestors, Ancestor{"2", "apps root"})},
+		{Id: "5", Title: "old app", Ancestors: append(ancestors, Ancestor{"2", "apps root"})},
+	})
+
+	if page := pt.GetPage("/apps/app1.yml"); page.GetRemoteId() != "3" || page.GetChangeType() != RENAME {
+		t.Errorf("Expected the page matched by resource id to be renamed")
+	}
+	if page := pt.GetPage("/docs/app2.yml"); page.GetRemoteId() != "4" || page.GetChangeType() != MOVE {
+		t.Errorf("Expected the page matched by title to be moved")
+	}
+	if deletes := pageUpdateToString(pt.deletes); deletes != "5" {
+		t.Errorf(`Expected only "5" to be deleted, got "%s"`, deletes)
+	}
+
+	waves := map[string]int{}
+	for i, group := range pt.GetChanges() {
+		for _, change := range group {
+			waves[change.Page.Key] = i
+		}
+	}
+	if waves["/docs/app2.yml"] <= waves["/docs"] {
+		t.Errorf("Expected the page to be moved after its new parent is created")
+	}
+}
+
+func TestAddRemotesDoesntMoveDeletedPages(t *testing.T) {
+	yr := []*YamlResource{
+		NewYamlResource("/docs", createYamlNode("wiki", "docs root")),
+		NewYamlResource("/docs/faq.yml", createYamlNode("wiki", "FAQ")),
+		NewYamlResource("/docs/setup.yml", createYamlNode("wiki", "Setup")),
+	}
+
+	pt := NewPageTree(yr, "1")
+	ancestors := []Ancestor{{"0", "space"}, {"1", "anchor"}}
+	pt.AddRemotes([]*RemoteResource{
+		{Id: "2", Title: "apps root", Ancestors: ancestors, ResourceId: RemoteResourceId{Value: "/apps"}},
+		// the resource of the page was deleted, a page with its title is added elsewhere
+		{Id: "3", Title: "FAQ", Ancestors: append(ancestors, Ancestor{"2", "apps root"}), ResourceId: RemoteResourceId{Value: "/apps/faq.yml"}},
+		// two pages published before resource ids were recorded share the title
+		{Id: "4", Title: "Setup", Ancestors: append(ancestors, Ancestor{"2", "apps root"})},
+		{Id: "5", Title: "Setup", Ancestors: ancestors},
+	})
+
+	for _, path := range []string{"/docs/faq.yml", "/docs/setup.yml"} {
+		if page := pt.GetPage(path); page.Remote != nil || page.GetChangeType() != CREATE {
+			t.Errorf("Expected %s to be created, got %v of %s", path, page.GetChangeType(), page.GetRemoteId())
+		}
+	}
+	if deletes := pageUpdateToString(pt.deletes); deletes != "34|2|5" {
+		t.Errorf(`Expected "34|2|5" to be deleted, got "%s"`, deletes)
+	}
+}
+
+func createOrderedNode(title string, order string) *yaml.Node {
+	return unmarshal([]byte(fmt.Sprintf("kind: wiki\ntitle: %s\n%s", title, order)))
+}
+
+func TestGetPositionChanges(t *testing.T) {
+	yr := []*YamlResource{
+		NewYamlResource("/docs", createOrderedNode("docs", "childOrder: [intro]")),
+		NewYamlResource("/docs/setup.yml", createOrderedNode("setup", "weight: 2")),
+		NewYamlResource("/docs/usage.yml", createOrderedNode("usage", "order: 1")),
+		NewYamlResource("/docs/faq.yml", createOrderedNode("faq", "")),
+		NewYamlResource("/docs/intro.yml", createOrderedNode("intro", "order: 9")),
+		NewYamlResource("/other.yml", createOrderedNode("other", "")),
+	}
+
+	pt := NewPageTree(yr, "1")
+	docs := pt.GetPage("/docs")
+
+	order := getKeys(docs.GetOrderedChildren())
+	expected := []string{"/docs/intro.yml", "/docs/usage.yml", "/docs/setup.yml", "/docs/faq.yml"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("Expected the order %v, got %v", expected, order)
+	}
+	if pt.rootPage.IsOrdered() {
+		t.Errorf("Expected the root page not to be ordered")
+	}
+
+	// the first two pages are in place, the others are moved after their preceding sibling
+	position := func(id string, previous string) *RemoteResource {
+		return &RemoteResource{Id: id, Position: RemotePosition{Id: "p" + id, Value: previous, Version: 1}}
+	}
+	pt.GetPage("/docs/intro.yml").Remote = position("11", POSITION_FIRST)
+	pt.GetPage("/docs/usage.yml").Remote = position("12", "/docs/intro.yml")
+	pt.GetPage("/docs/setup.yml").Remote = position("13", "/docs/faq.yml")
+	pt.GetPage("/docs/faq.yml").Remote = position("14", "/docs/setup.yml")
+
+	positions := docs.GetPositionChanges()
+	if len(positions) != 2 || positions[0].Page.Key != "/docs/setup.yml" || positions[0].Position != "after" || positions[0].Target.Key != "/docs/usage.yml" || positions[1].Target.Key != "/docs/setup.yml" {
+		t.Errorf("Expected setup and faq to be moved, got %+v", positions)
+	}
+	if property := positions[1].Page.GetPositionProperty(positions[1].Previous); property.GetValue() != "/docs/setup.yml" || !property.IsUpdate() {
+		t.Errorf("Expected the position of faq to be updated, got %+v", property)
+	}
+
+	changes := pt.GetChanges()
+	orders := changes[len(changes)-2]
+	if len(orders) != 1 || orders[0].Operation != ORDER || orders[0].Page != docs {
+		t.Errorf("Expected the docs page to be ordered in the last wave before skips, got %+v", orders)
+	}
+
+	pt.GetPage("/docs/setup.yml").Remote = position("13", "/docs/usage.yml")
+	pt.GetPage("/docs/faq.yml").Remote = position("14", "/docs/setup.yml")
+	if positions := docs.GetPositionChanges(); len(positions) != 0 {
+		t.Errorf("Expected no position changes, got %+v", positions)
+	}
+}
@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -35,6 +36,12 @@ func PrettyPrint(target RenderTarget, page *Page, w *os.File) {
 		prettyPrintJson(page.Resource.ToOrderedMap(), w)
 	case MST:
 		fmt.Fprintln(w, page.Content.Markup)
+	case ADF:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(page.Content.Markup), "", "  "); err != nil {
+			panic(err)
+		}
+		fmt.Fprintln(w, buf.String())
 	}
 }
 
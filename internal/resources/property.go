@@ -1,18 +1,23 @@
 package resources
 
 type Property struct {
-	id      string
-	key     string
-	value   string
-	version int
+	id         string
+	propertyId string
+	key        string
+	value      string
+	version    int
 }
 
-func NewProperty(id, key, value string, version int) Property {
-	return Property{id, key, value, version}
+// NewProperty creates a property of the content with id, propertyId is empty when it doesn't exist yet
+func NewProperty(id, propertyId, key, value string, version int) Property {
+	return Property{id, propertyId, key, value, version}
 }
 func (p Property) GetId() string {
 	return p.id
 }
+func (p Property) GetPropertyId() string {
+	return p.propertyId
+}
 func (p Property) GetKey() string {
 	return p.key
 }
@@ -0,0 +1,183 @@
+package resources
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PulledPage is a Confluence page imported into the space directory
+type PulledPage struct {
+	Id       string
+	Title    string
+	Labels   []string
+	Body     string
+	Children []*PulledPage
+	// File is the resource written for the page, pages with children become a directory with an _index.yml
+	File string
+	// AttachmentsDir holds the downloaded attachments, the names of the files are listed in Attachments
+	AttachmentsDir string
+	Attachments    []string
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// kinds whose template outputs the markup field unchanged, their pages can be written back to the resource
+var VERBATIM_KINDS = []string{"wiki", "storage"}
+
+// GetResourceFile returns the file a resource is loaded from, directories return their index file.
+// The path is empty for directories without index file.
+func GetResourceFile(spaceDir string, yr *YamlResource) string {
+	if IsResourceFile(yr.Path) {
+		return filepath.Join(spaceDir, yr.Path)
+	}
+
+	return findIndexFile(filepath.Join(spaceDir, yr.Path))
+}
+
+// PullStorageBody replaces the body of a resource with a body in storage representation taken from Confluence.
+// Only YAML resources of a verbatim kind can be pulled, other kinds are rendered from a template.
+func PullStorageBody(spaceDir string, yr *YamlResource, body string) error {
+	if !isOneOf(yr.Kind, VERBATIM_KINDS) {
+		return errors.New(fmt.Sprintf("Pages of kind '%s' are rendered from a template and can't be pulled", yr.Kind))
+	}
+
+	file := GetResourceFile(spaceDir, yr)
+	if file == "" {
+		file = filepath.Join(spaceDir, yr.Path, "_index.yml")
+	}
+	if IsMarkdownFile(file) {
+		return errors.New(fmt.Sprintf("%s is a markdown file and can't be pulled", file))
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	if data, err := os.ReadFile(file); err == nil {
+		doc = unmarshal(data)
+	}
+	if doc.Kind == 0 {
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	mapping := doc.Content[0]
+
+	setMappingValue(mapping, "kind", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "storage"})
+	if getMappingValue(mapping, "title") == nil {
+		setMappingValue(mapping, "title", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: yr.Title})
+	}
+	if representation := getMappingValue(mapping, "representation"); representation != nil {
+		representation.Value = STORAGE_REPRESENTATION
+	}
+	setMappingValue(mapping, "markup", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: body, Style: yaml.LiteralStyle})
+
+	return writeYaml(file, doc)
+}
+
+func writeYaml(file string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	encoder.Close()
+
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
+
+// LayoutPulledPages assigns the files of the pages below dir. Every page is named after its title, unique among its siblings.
+func LayoutPulledPages(dir string, pages []*PulledPage) {
+	names := map[string]bool{}
+
+	for _, page := range pages {
+		name := getFileName(page.Title)
+		for i := 2; names[name]; i++ {
+			name = fmt.Sprintf("%s-%d", getFileName(page.Title), i)
+		}
+		names[name] = true
+
+		if len(page.Children) > 0 {
+			page.File = filepath.Join(dir, name, "_index.yml")
+			page.AttachmentsDir = filepath.Join(dir, name, "_attachments", "_index")
+			LayoutPulledPages(filepath.Join(dir, name), page.Children)
+		} else {
+			page.File = filepath.Join(dir, name+".yml")
+			page.AttachmentsDir = filepath.Join(dir, "_attachments", name)
+		}
+	}
+}
+
+// getFileName turns a title into a lower case file name, leading underscores and dots are dropped because
+// those files are ignored when resources are loaded
+func getFileName(title string) string {
+	name := strings.Trim(unsafeFileNameChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	name = strings.TrimLeft(name, "_.")
+	if name == "" {
+		return "page"
+	}
+	// index files hold the resource of their directory
+	if strings.Split(name, ".")[0] == "index" {
+		return "index-page"
+	}
+
+	return name
+}
+
+// WritePulledPage writes the page as a wiki resource holding its storage format body. Existing files are
+// only replaced when overwrite is true.
+// LoadPulledResource loads the resource written for the page the way an upload of the space loads it
+func LoadPulledResource(spaceDir string, page *PulledPage) *YamlResource {
+	relPath := strings.TrimPrefix(page.File, spaceDir)
+	yr := DefaultYamlResourceLoader().LoadYamlResource(spaceDir, relPath)
+	// the resource of an index file is its directory
+	if isIndexFile(relPath) {
+		yr.Path = filepath.Dir(relPath)
+	}
+
+	return yr
+}
+
+func WritePulledPage(page *PulledPage, overwrite bool) error {
+	if _, err := os.Stat(page.File); err == nil && !overwrite {
+		return errors.New(fmt.Sprintf("%s already exists", page.File))
+	}
+
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setMappingValue(mapping, "kind", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "wiki"})
+	setMappingValue(mapping, "title", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: page.Title})
+	setMappingValue(mapping, "representation", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: STORAGE_REPRESENTATION})
+	if len(page.Labels) > 0 {
+		setMappingValue(mapping, "labels", toSequenceNode(page.Labels))
+	}
+	if len(page.Attachments) > 0 {
+		attachments := []string{}
+		for _, name := range page.Attachments {
+			rel, err := filepath.Rel(filepath.Dir(page.File), filepath.Join(page.AttachmentsDir, name))
+			if err != nil {
+				return err
+			}
+			attachments = append(attachments, filepath.ToSlash(rel))
+		}
+		setMappingValue(mapping, "attachments", toSequenceNode(attachments))
+	}
+	setMappingValue(mapping, "markup", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: page.Body, Style: yaml.LiteralStyle})
+
+	if err := os.MkdirAll(filepath.Dir(page.File), 0755); err != nil {
+		return err
+	}
+
+	return writeYaml(page.File, &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{mapping}})
+}
+
+func toSequenceNode(values []string) *yaml.Node {
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, v := range values {
+		seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v})
+	}
+
+	return seq
+}
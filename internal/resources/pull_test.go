@@ -0,0 +1,81 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPullStorageBody(t *testing.T) {
+	spaceDir := t.TempDir()
+	file := filepath.Join(spaceDir, "page.yml")
+	os.WriteFile(file, []byte("kind: wiki\ntitle: Page\nlabels:\n  - docs\nmarkup: h1. Old\n"), 0644)
+
+	yr := NewYamlResource("/page.yml", createYamlNode("wiki", "Page"))
+	if err := PullStorageBody(spaceDir, yr, "<h1>New</h1>"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(file)
+	expected := "kind: storage\ntitle: Page\nlabels:\n  - docs\nmarkup: |-\n  <h1>New</h1>\n"
+	if string(data) != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, string(data))
+	}
+
+	yr = NewYamlResource("/app.yml", createYamlNode("application", "App"))
+	if err := PullStorageBody(spaceDir, yr, "<p>body</p>"); err == nil {
+		t.Errorf("Expected templated kinds to be rejected")
+	}
+}
+
+func TestLayoutPulledPages(t *testing.T) {
+	dir := t.TempDir()
+	pages := []*PulledPage{
+		{Title: "Team Docs", Children: []*PulledPage{{Title: "How/To"}}},
+		{Title: "team docs"},
+		{Title: "_Index"},
+	}
+
+	LayoutPulledPages(dir, pages)
+
+	expected := map[string]string{
+		"Team Docs": filepath.Join(dir, "team-docs", "_index.yml"),
+		"How/To":    filepath.Join(dir, "team-docs", "how-to.yml"),
+		"team docs": filepath.Join(dir, "team-docs-2.yml"),
+		"_Index":    filepath.Join(dir, "index-page.yml"),
+	}
+	for _, page := range []*PulledPage{pages[0], pages[0].Children[0], pages[1], pages[2]} {
+		if page.File != expected[page.Title] {
+			t.Errorf("Expected %s to be written to %s, got %s", page.Title, expected[page.Title], page.File)
+		}
+	}
+	if pages[0].AttachmentsDir != filepath.Join(dir, "team-docs", "_attachments", "_index") {
+		t.Errorf("Unexpected attachments directory %s", pages[0].AttachmentsDir)
+	}
+}
+
+func TestWritePulledPage(t *testing.T) {
+	dir := t.TempDir()
+	page := &PulledPage{
+		Title:          "Page",
+		Labels:         []string{"docs"},
+		Body:           "<p>body</p>",
+		File:           filepath.Join(dir, "page.yml"),
+		AttachmentsDir: filepath.Join(dir, "_attachments", "page"),
+		Attachments:    []string{"diagram.png"},
+	}
+
+	if err := WritePulledPage(page, false); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(page.File)
+	expected := "kind: wiki\ntitle: Page\nrepresentation: storage\nlabels:\n  - docs\nattachments:\n  - _attachments/page/diagram.png\nmarkup: |-\n  <p>body</p>\n"
+	if string(data) != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, string(data))
+	}
+
+	if err := WritePulledPage(page, false); err == nil {
+		t.Errorf("Expected existing files to be kept without overwrite")
+	}
+}
@@ -1,13 +1,22 @@
 package resources
 
 type RemoteResource struct {
-	Id        string
-	Title     string
-	Labels    []string
-	Link      string
-	Version   int
-	Ancestors []Ancestor
-	Sha256    RemoteSha256
+	Id          string
+	Title       string
+	Labels      []string
+	Link        string
+	Version     int
+	Ancestors   []Ancestor
+	Sha256      RemoteSha256
+	Attachments RemoteAttachments
+	// PublishedVersion is the version of the page written by the last upload
+	PublishedVersion RemotePublishedVersion
+	// ResourceId is the identity of the resource the page was published from
+	ResourceId RemoteResourceId
+	// Position is the resource id of the preceding sibling when y2c last ordered the page
+	Position RemotePosition
+	// LastModifier is the account id (cloud) or user name (server) of the author of the current version
+	LastModifier string
 }
 
 type Ancestor struct {
@@ -21,6 +30,43 @@ type RemoteSha256 struct {
 	Version int
 }
 
+type RemoteResourceId struct {
+	Id      string
+	Value   string
+	Version int
+}
+
+type RemotePosition struct {
+	Id      string
+	Value   string
+	Version int
+}
+
+type RemotePublishedVersion struct {
+	Id      string
+	Value   int
+	Version int
+}
+
+// IsEditedManually reports whether someone changed the page after y2c published it. Pages published before
+// the version was recorded are compared by the author of their current version instead.
+func (rr *RemoteResource) IsEditedManually(user string) bool {
+	if rr.PublishedVersion.Value > 0 {
+		return rr.Version > rr.PublishedVersion.Value
+	}
+
+	return user != "" && rr.LastModifier != "" && rr.LastModifier != user
+}
+
+// GetParentId returns the id of the parent page, empty when the ancestors were not retrieved
+func (rr *RemoteResource) GetParentId() string {
+	if len(rr.Ancestors) == 0 {
+		return ""
+	}
+
+	return rr.Ancestors[len(rr.Ancestors)-1].Id
+}
+
 func (rr *RemoteResource) GetTitlePath(anchorId string) []string {
 	titlePath := []string{}
 	startIndex := 1 // first page after space page
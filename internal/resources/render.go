@@ -3,13 +3,14 @@ package resources
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"hash"
 	"os"
 	"path/filepath"
 
 	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
-	"github.com/cbroglie/mustache"
 )
 
 type RenderTarget uint32
@@ -18,6 +19,8 @@ const (
 	YAML = 1 << iota
 	JSON
 	MST
+	// ADF renders like MST, the page must use the atlas_doc_format representation
+	ADF
 )
 
 type RenderTools struct {
@@ -25,6 +28,8 @@ type RenderTools struct {
 	templates *TemplateProcessor
 	hooks     *HookProcessor
 	hasher    hash.Hash
+	// pt is the page tree being rendered by RenderAll, links are resolved through it
+	pt *PageTree
 }
 
 func NewRenderTools(dirProps utils.DirectoryProperties, precompileJqHooks bool) *RenderTools {
@@ -37,6 +42,15 @@ func NewRenderTools(dirProps utils.DirectoryProperties, precompileJqHooks bool)
 	return &rt
 }
 
+// ForSpace returns render tools for another space of the instance, the templates and hooks are shared
+func (rt *RenderTools) ForSpace(dirProps utils.DirectoryProperties) *RenderTools {
+	return &RenderTools{
+		dirProps:  dirProps,
+		templates: rt.templates,
+		hooks:     rt.hooks,
+	}
+}
+
 // func (rt *RenderTools) GetTemplate(kind string) string {
 // 	template, exists := rt.templates[kind]
 // 	if !exists {
@@ -48,6 +62,12 @@ func NewRenderTools(dirProps utils.DirectoryProperties, precompileJqHooks bool)
 // }
 
 func (rt *RenderTools) RenderTo(target RenderTarget, p *Page) {
+	rt.renderResource(target, p)
+	rt.renderMarkup(p)
+}
+
+// renderResource runs the hooks of the resource, its title is final once they ran
+func (rt *RenderTools) renderResource(target RenderTarget, p *Page) {
 	hookset := rt.hooks.GetHookSet(p.Resource.Kind)
 
 	hookset.Ls.Run()
@@ -75,33 +95,129 @@ func (rt *RenderTools) RenderTo(target RenderTarget, p *Page) {
 			p.Resource.Json = res
 		}
 		p.Resource.UpdateKindAndTitle()
-		fallthrough
-	case target == MST:
-		template, err := rt.templates.Get(p.Resource.Kind)
-		if err != nil {
-			fmt.Printf("Failed to render %s\n%s", filepath.Join(rt.dirProps.SpaceDir, p.Resource.Path), err.Error())
-			os.Exit(1)
-		}
-		renderContent(p, template, hookset.Header, hookset.Footer)
 	}
 }
 
+// renderMarkup renders the template of the resource into the content of the page
+func (rt *RenderTools) renderMarkup(p *Page) {
+	hookset := rt.hooks.GetHookSet(p.Resource.Kind)
+
+	template, err := rt.templates.Get(p.Resource.Kind)
+	if err != nil {
+		fmt.Printf("Failed to render %s\n%s", filepath.Join(rt.dirProps.SpaceDir, p.Resource.Path), err.Error())
+		os.Exit(1)
+	}
+	representation := p.Resource.GetRepresentation()
+	if representation == "" {
+		representation = rt.templates.GetRepresentation(p.Resource.Kind)
+	}
+	if !IsValidRepresentation(representation) {
+		fmt.Printf("Failed to render %s\nUnknown representation '%s', expected one of %v\n", filepath.Join(rt.dirProps.SpaceDir, p.Resource.Path), representation, REPRESENTATIONS)
+		os.Exit(1)
+	}
+	links := &linkRenderer{rt: rt, page: p, representation: representation}
+	if err := renderContent(p, template, representation, hookset.Header, hookset.Footer, links); err != nil {
+		fmt.Printf("Failed to render %s\n%s\n", filepath.Join(rt.dirProps.SpaceDir, p.Resource.Path), err.Error())
+		os.Exit(1)
+	}
+	attachments, err := ResolveAttachments(filepath.Join(rt.dirProps.SpaceDir, p.Resource.GetSourceDir()), p.Resource.GetAttachments())
+	if err != nil {
+		fmt.Printf("Failed to render %s\n%s\n", filepath.Join(rt.dirProps.SpaceDir, p.Resource.Path), err.Error())
+		os.Exit(1)
+	}
+	p.Attachments = attachments
+}
+
+// RenderAll renders the pages of the tree. The hooks of all pages run before any template is rendered, so links
+// use the final titles of the pages they point to.
 func (rt *RenderTools) RenderAll(pt *PageTree) {
-	for _, page := range pt.GetPages() {
-		rt.RenderTo(MST, page)
+	// hooks list files relative to the space being rendered
+	os.Setenv("SPACE_DIR", rt.dirProps.SpaceDir)
+	rt.pt = pt
+
+	pages := pt.GetPages()
+	if homepage := pt.GetHomepage(); homepage != nil {
+		pages = append([]*Page{homepage}, pages...)
+	}
+
+	for _, page := range pages {
+		rt.renderResource(MST, page)
+	}
+	for _, page := range pages {
+		rt.renderMarkup(page)
 	}
 }
 
-func renderContent(p *Page, template string, header string, footer string) {
-	// TODO handle error
-	p.Content.Markup, _ = mustache.Render(template, p.Resource.ToObject())
+func renderContent(p *Page, template string, representation string, header string, footer string, links *linkRenderer) error {
+	if representation == ADF_REPRESENTATION {
+		return renderAdfContent(p, template, header, footer, links)
+	}
+
+	markup, err := renderTemplate(template, links, p.Resource.ToObject())
+	if err != nil {
+		return err
+	}
+	p.Content.Markup = markup
 	if header != "" {
 		p.Content.Markup = header + "\n" + p.Content.Markup
 	}
 	if footer != "" {
 		p.Content.Markup += "\n" + footer
 	}
+	if representation == MARKDOWN_REPRESENTATION {
+		storage, err := MarkdownToStorage(p.Content.Markup)
+		if err != nil {
+			return err
+		}
+		p.Content.Markup = storage
+		representation = STORAGE_REPRESENTATION
+	}
+	p.Content.Representation = representation
+	p.Content.Sha256 = hashContent(p.Content.Markup, representation)
+
+	return nil
+}
+
+// renderAdfContent renders an ADF document, an adf field holding a JSON object (e.g. set by a jq hook)
+// is passed to the template as JSON text
+func renderAdfContent(p *Page, template string, header string, footer string, links *linkRenderer) error {
+	if header != "" || footer != "" {
+		return errors.New("Hook headers and footers can't be added to atlas_doc_format pages")
+	}
+
+	obj := p.Resource.ToObject()
+	switch adf := obj["adf"].(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(adf)
+		if err != nil {
+			return err
+		}
+		obj["adf"] = string(data)
+	}
+
+	document, err := renderTemplate(template, links, obj)
+	if err != nil {
+		return err
+	}
+	markup, err := ValidateAdf(document)
+	if err != nil {
+		return err
+	}
+
+	p.Content.Markup = markup
+	p.Content.Representation = ADF_REPRESENTATION
+	p.Content.Sha256 = hashContent(markup, ADF_REPRESENTATION)
+
+	return nil
+}
+
+func hashContent(markup string, representation string) string {
 	hasher := sha256.New()
-	hasher.Write([]byte(p.Content.Markup))
-	p.Content.Sha256 = hex.EncodeToString(hasher.Sum(nil))
+	// the representation is part of what is sent, wiki is left out so existing hashes remain valid
+	if representation != WIKI_REPRESENTATION {
+		hasher.Write([]byte(representation + "\n"))
+	}
+	hasher.Write([]byte(markup))
+
+	return hex.EncodeToString(hasher.Sum(nil))
 }
@@ -71,9 +71,12 @@ func (yrl YamlResourceLoader) loadYamlResources(dir string) []*YamlResource {
 				// save a pointer to the directory YamlResource for later in case an index.yml is found
 				parents[relPath] = yr
 				yrs = append(yrs, yr)
-			} else if IsYamlFile(path) {
+			} else if relPath == string(os.PathSeparator)+SPACE_FILE {
+				// the configuration of the space is not a page
+				return nil
+			} else if IsResourceFile(path) {
 				yr := yrl.LoadYamlResource(dir, relPath)
-				if isIndexFile(path) {
+				if isIndexFile(path) && !IsHomepageFile(relPath) {
 					parent := parents[filepath.Dir(relPath)]
 					parent.Kind = yr.Kind
 					parent.Title = yr.Title
@@ -104,8 +107,128 @@ func LoadSingleYamlResource(file string) *YamlResource {
 	return yrl.loadYamlResources(utils.GetDirectoryProperties(file).SpaceDir)[0]
 }
 
+// LoadYamlResourceChain loads a single resource together with the directory resources of all of its
+// ancestors, ordered from the top level directory down. The requested resource is always the last element.
+func LoadYamlResourceChain(file string) []*YamlResource {
+	fileAbs := utils.ResolveAbsolutePathFile(file)
+	spaceDir := utils.GetDirectoryProperties(file).SpaceDir
+
+	yrl := YamlResourceLoader{func(root string, fn filepath.WalkFunc) error {
+		for _, dir := range getAncestorDirs(root, fileAbs) {
+			info, err := os.Stat(dir)
+			if err := fn(dir, info, err); err != nil {
+				return errors.New(fmt.Sprintf("%s is inside of ignored directory %s", fileAbs, dir))
+			}
+
+			if index := findIndexFile(dir); index != "" && index != fileAbs {
+				info, err := os.Stat(index)
+				fn(index, info, err)
+			}
+		}
+
+		info, err := os.Stat(fileAbs)
+		return fn(fileAbs, info, err)
+	}, DefaultLoadYaml}
+
+	return yrl.loadYamlResources(spaceDir)
+}
+
+// getAncestorDirs returns every directory between spaceDir (exclusive) and the directory containing file
+func getAncestorDirs(spaceDir, file string) []string {
+	dirs := []string{}
+	for dir := filepath.Dir(file); len(dir) > len(spaceDir); dir = filepath.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+
+	return dirs
+}
+
+func findIndexFile(dir string) string {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		panic(err)
+	}
+
+	for _, m := range matches {
+		if isIndexFile(m) {
+			return m
+		}
+	}
+
+	return ""
+}
+
 func (yrl YamlResourceLoader) LoadYamlResource(spaceRootDir, relFilePath string) *YamlResource {
-	return NewYamlResource(relFilePath, unmarshal(yrl.LoadYaml(filepath.Join(spaceRootDir, relFilePath))))
+	data := yrl.LoadYaml(filepath.Join(spaceRootDir, relFilePath))
+	if IsMarkdownFile(relFilePath) {
+		return NewYamlResource(relFilePath, unmarshalMarkdown(relFilePath, data))
+	}
+
+	return NewYamlResource(relFilePath, unmarshal(data))
+}
+
+// unmarshalMarkdown uses the front matter of a markdown file as the resource, the rest of the file becomes
+// its markdown field. The kind defaults to markdown and the title to the file name.
+func unmarshalMarkdown(path string, data []byte) *yaml.Node {
+	frontMatter, body := splitFrontMatter(strings.ReplaceAll(string(data), "\r\n", "\n"))
+
+	doc := unmarshal([]byte(frontMatter))
+	if doc.Kind == 0 {
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	mapping := doc.Content[0]
+
+	setMappingValue(mapping, "markdown", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: body, Style: yaml.LiteralStyle})
+	if getMappingValue(mapping, "kind") == nil {
+		setMappingValue(mapping, "kind", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "markdown"})
+	}
+	// the homepage keeps its title unless the front matter sets one
+	if getMappingValue(mapping, "title") == nil && !IsHomepageFile(path) {
+		title := fileNameWithoutExtension(path)
+		if isIndexFile(path) {
+			title = filepath.Base(filepath.Dir(path))
+		}
+		setMappingValue(mapping, "title", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: title})
+	}
+
+	return doc
+}
+
+func splitFrontMatter(content string) (string, string) {
+	if !strings.HasPrefix(content, "---\n") {
+		return "", content
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		if delimiter := strings.TrimRight(lines[i], "\n"); delimiter == "---" || delimiter == "..." {
+			return strings.Join(lines[1:i], ""), strings.Join(lines[i+1:], "")
+		}
+	}
+
+	// no closing delimiter, there is no front matter
+	return "", content
+}
+
+func getMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
 }
 
 func IsYamlFile(file string) bool {
@@ -113,9 +236,24 @@ func IsYamlFile(file string) bool {
 	return ext == ".yml" || ext == ".yaml"
 }
 
+func IsMarkdownFile(file string) bool {
+	return filepath.Ext(file) == ".md"
+}
+
+// IsResourceFile is true for files in the space directory that are loaded as pages
+func IsResourceFile(file string) bool {
+	return IsYamlFile(file) || IsMarkdownFile(file)
+}
+
 func isIndexFile(file string) bool {
 	name := strings.Split(filepath.Base(file), ".")[0]
-	return IsYamlFile(file) && (name == "index" || name == "_index")
+	return IsResourceFile(file) && (name == "index" || name == "_index")
+}
+
+// IsHomepageFile is true for the index file at the root of the space directory, it is rendered into the homepage
+// of the space, or into the anchor page
+func IsHomepageFile(relPath string) bool {
+	return isIndexFile(relPath) && filepath.Dir(relPath) == string(os.PathSeparator)
 }
 
 func ignoreDir(path string) bool {
@@ -143,6 +281,23 @@ func EnsureUniqueTitles(yrs []*YamlResource) error {
 	return nil
 }
 
+// EnsureUniqueResourceIds rejects resources sharing an id, they would be matched to the same page
+func EnsureUniqueResourceIds(yrs []*YamlResource) error {
+	uniqueId := map[string]*YamlResource{}
+
+	for _, cur := range yrs {
+		if _, err := cur.getResourceId(); err != nil {
+			return err
+		}
+		id := cur.GetResourceId()
+		if r, exists := uniqueId[id]; exists {
+			return errors.New(fmt.Sprintf(constants.DUPLICATE_ID, id, r.Path, cur.Path))
+		}
+		uniqueId[id] = cur
+	}
+	return nil
+}
+
 func GetAnchor(spaceDir string) string {
 	data, err := os.ReadFile(filepath.Join(spaceDir, ".anchor"))
 	if err != nil {
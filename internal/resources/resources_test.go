@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -84,15 +85,18 @@ func printYamlResources(e []*YamlResource, a []*YamlResource) string {
 func TestLoadYamlResources(t *testing.T) {
 	paths := [][]interface{}{
 		{"/home/user/confluence/spaces/DEMO", true},
+		{"/home/user/confluence/spaces/DEMO/_index.yml", false, "wiki", "Home"},
 		{"/home/user/confluence/spaces/DEMO/apps", true},
 		{"/home/user/confluence/spaces/DEMO/apps/app1.yml", false, "application", "Test Application 1"},
 		{"/home/user/confluence/spaces/DEMO/apps/index.yml", false, "index", "Applications"},
 		{"/home/user/confluence/spaces/DEMO/apps/nested", true},
 		{"/home/user/confluence/spaces/DEMO/apps/nested/app2.yml", false, "application", "Test Application 2"},
 		{"/home/user/confluence/spaces/DEMO/freeform.yml", false, "wiki", "Wiki Example"},
+		{"/home/user/confluence/spaces/DEMO/space.yml", false, "", ""},
 	}
 
 	expected := []*YamlResource{
+		NewYamlResource("/_index.yml", createYamlNode("wiki", "Home")),
 		NewYamlResource("/apps", createYamlNode("index", "Applications")),
 		NewYamlResource("/apps/app1.yml", createYamlNode("application", "Test Application 1")),
 		NewYamlResource("/apps/nested", createYamlNode("wiki", "nested")),
@@ -128,3 +132,27 @@ func TestEnsureUniqueTitles(t *testing.T) {
 		t.Fatalf(`Expected error of "%s", got "%s"`, expectedErrMsg, err.Error())
 	}
 }
+
+func TestGetResourceId(t *testing.T) {
+	ids := map[string]string{
+		"":            "/app.yml",
+		"id: app-1":   "app-1",
+		"id: 42":      "42",
+		"id: \"042\"": "042",
+		"id: true":    "true",
+	}
+	for field, expected := range ids {
+		yr := NewYamlResource("/app.yml", unmarshal([]byte("kind: wiki\ntitle: App\n"+field)))
+		if id := yr.GetResourceId(); id != expected {
+			t.Errorf("Expected %q to have the id %s, got %s", field, expected, id)
+		}
+	}
+
+	yrs := []*YamlResource{
+		NewYamlResource("/app.yml", unmarshal([]byte("kind: wiki\ntitle: App\nid: 42"))),
+		NewYamlResource("/other.yml", unmarshal([]byte("kind: wiki\ntitle: Other\nid: [1, 2]"))),
+	}
+	if err := EnsureUniqueResourceIds(yrs); err == nil || !strings.Contains(err.Error(), "/other.yml") {
+		t.Errorf("Expected a list id to be rejected with the path of its resource, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SPACE_FILE is the optional configuration of a space, at the root of its space directory
+const SPACE_FILE = "space.yml"
+
+const (
+	SPACE_TYPE_GLOBAL = "global"
+	// SPACE_TYPE_PRIVATE is a global space only visible to its creator until permissions are granted
+	SPACE_TYPE_PRIVATE = "private"
+)
+
+const (
+	ACCESS_VIEW  = "view"
+	ACCESS_EDIT  = "edit"
+	ACCESS_ADMIN = "admin"
+)
+
+var SPACE_TYPES = []string{SPACE_TYPE_GLOBAL, SPACE_TYPE_PRIVATE}
+var SPACE_ACCESS = []string{ACCESS_VIEW, ACCESS_EDIT, ACCESS_ADMIN}
+
+// SpaceConfig is the content of space.yml. Name, description and type are used when the space is created, name,
+// description and permissions are reconciled by later uploads.
+type SpaceConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Type is global or private, it can't be changed once the space exists
+	Type string `yaml:"type"`
+	// Homepage is the resource file, relative to the space directory, rendered into the homepage of the space. It
+	// replaces the index file at the root of the space directory.
+	Homepage    string            `yaml:"homepage"`
+	Permissions []SpacePermission `yaml:"permissions"`
+}
+
+// SpacePermission grants view, edit or admin access to a group or a user (account id on cloud)
+type SpacePermission struct {
+	Group  string   `yaml:"group"`
+	User   string   `yaml:"user"`
+	Access []string `yaml:"access"`
+}
+
+// LoadSpaceConfig reads the space.yml of a space directory, an empty config is returned when there is none
+func LoadSpaceConfig(spaceDir string) (*SpaceConfig, error) {
+	config := &SpaceConfig{}
+
+	data, err := os.ReadFile(filepath.Join(spaceDir, SPACE_FILE))
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid %s\n%s", SPACE_FILE, err.Error()))
+	}
+	if err := config.validate(spaceDir); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid %s\n%s", SPACE_FILE, err.Error()))
+	}
+
+	return config, nil
+}
+
+func (sc *SpaceConfig) validate(spaceDir string) error {
+	if sc.Type == "personal" {
+		return errors.New("Personal spaces can't be created through the API, use type private for a space only visible to its creator")
+	}
+	if sc.Type != "" && !contains(SPACE_TYPES, sc.Type) {
+		return errors.New(fmt.Sprintf("Unknown type '%s', expected one of %v", sc.Type, SPACE_TYPES))
+	}
+
+	if sc.Homepage != "" {
+		if !IsResourceFile(sc.Homepage) || isIndexFile(sc.Homepage) {
+			return errors.New(fmt.Sprintf("The homepage %s is not a resource file", sc.Homepage))
+		}
+		if _, err := os.Stat(filepath.Join(spaceDir, sc.Homepage)); err != nil {
+			return errors.New(fmt.Sprintf("The homepage %s does not exist", sc.Homepage))
+		}
+		if index := findIndexFile(spaceDir); index != "" {
+			return errors.New(fmt.Sprintf("The homepage %s conflicts with %s, only one of them can be rendered into the homepage", sc.Homepage, filepath.Base(index)))
+		}
+	}
+
+	for _, permission := range sc.Permissions {
+		if (permission.Group == "") == (permission.User == "") {
+			return errors.New("A permission requires either a group or a user")
+		}
+		for _, access := range permission.Access {
+			if !contains(SPACE_ACCESS, access) {
+				return errors.New(fmt.Sprintf("Unknown access '%s' for %s, expected one of %v", access, permission.GetSubject(), SPACE_ACCESS))
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetHomepagePath returns the resource path of the homepage, empty when space.yml doesn't declare one
+func (sc *SpaceConfig) GetHomepagePath() string {
+	if sc.Homepage == "" {
+		return ""
+	}
+
+	return string(os.PathSeparator) + strings.TrimPrefix(filepath.Clean(sc.Homepage), string(os.PathSeparator))
+}
+
+// -------------------------
+// SpaceContext functions
+// -------------------------
+
+func (sc *SpaceConfig) GetName() string {
+	return sc.Name
+}
+func (sc *SpaceConfig) GetDescription() string {
+	return sc.Description
+}
+func (sc *SpaceConfig) IsPrivate() bool {
+	return sc.Type == SPACE_TYPE_PRIVATE
+}
+
+// GetConfluenceType returns the type of the space in Confluence, private spaces are global spaces with restricted
+// permissions. It is empty when space.yml has no type.
+func (sc *SpaceConfig) GetConfluenceType() string {
+	if sc.Type == "" {
+		return ""
+	}
+
+	return SPACE_TYPE_GLOBAL
+}
+
+// GetSubjectType returns group or user
+func (sp SpacePermission) GetSubjectType() string {
+	if sp.Group != "" {
+		return "group"
+	}
+
+	return "user"
+}
+
+// GetSubjectName returns the group name or the user of the permission
+func (sp SpacePermission) GetSubjectName() string {
+	if sp.Group != "" {
+		return sp.Group
+	}
+
+	return sp.User
+}
+
+func (sp SpacePermission) GetSubject() string {
+	return sp.GetSubjectType() + " " + sp.GetSubjectName()
+}
+
+// SplitHomepage removes the homepage resource from the resources of a space, it is nil when it isn't part of them.
+// The homepage is the resource at path, or the index file at the root of the space directory when path is empty.
+func SplitHomepage(yrs []*YamlResource, path string) (*YamlResource, []*YamlResource) {
+	rest := []*YamlResource{}
+	var homepage *YamlResource
+	for _, yr := range yrs {
+		if yr.Path == path || (path == "" && IsHomepageFile(yr.Path)) {
+			homepage = yr
+		} else {
+			rest = append(rest, yr)
+		}
+	}
+
+	return homepage, rest
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSpaceConfig(t *testing.T) {
+	spaceDir := t.TempDir()
+
+	config, err := LoadSpaceConfig(spaceDir)
+	if err != nil || config.Name != "" || len(config.Permissions) != 0 {
+		t.Errorf("Expected an empty config without %s, got %+v %v", SPACE_FILE, config, err)
+	}
+
+	os.WriteFile(filepath.Join(spaceDir, "home.yml"), []byte("kind: wiki\ntitle: Home\n"), 0644)
+	os.WriteFile(filepath.Join(spaceDir, SPACE_FILE), []byte(`name: Team Docs
+description: Documentation of the team
+type: global
+homepage: ./home.yml
+permissions:
+  - group: developers
+    access: [view, edit]
+`), 0644)
+
+	config, err = LoadSpaceConfig(spaceDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "Team Docs" || config.IsPrivate() {
+		t.Errorf("Unexpected config %+v", config)
+	}
+	if config.GetHomepagePath() != string(os.PathSeparator)+"home.yml" {
+		t.Errorf("Expected the homepage path /home.yml, got %s", config.GetHomepagePath())
+	}
+	if subject := config.Permissions[0].GetSubject(); subject != "group developers" {
+		t.Errorf("Expected the subject group developers, got %s", subject)
+	}
+
+	invalid := []string{
+		"type: team\n",
+		"type: personal\n",
+		"homepage: missing.yml\n",
+		"permissions:\n  - access: [view]\n",
+		"permissions:\n  - user: abc\n    access: [write]\n",
+	}
+	for _, content := range invalid {
+		os.WriteFile(filepath.Join(spaceDir, SPACE_FILE), []byte(content), 0644)
+		if _, err := LoadSpaceConfig(spaceDir); err == nil {
+			t.Errorf("Expected %q to be rejected", content)
+		}
+	}
+}
+
+func TestSplitHomepage(t *testing.T) {
+	yrs := []*YamlResource{
+		NewYamlResource("/home.yml", createYamlNode("wiki", "Home")),
+		NewYamlResource("/page.yml", createYamlNode("wiki", "Page")),
+	}
+
+	homepage, rest := SplitHomepage(yrs, "/home.yml")
+	if homepage == nil || homepage.Title != "Home" || len(rest) != 1 {
+		t.Errorf("Expected the homepage to be split from the resources")
+	}
+
+	pt := NewPageTree(rest, "100")
+	pt.SetHomepage(homepage)
+	pt.SetHomepageRemote(&RemoteResource{Id: "100", Title: "TEAM Home"})
+	pt.AddRemotes([]*RemoteResource{{Id: "100", Title: "Home"}})
+
+	changes := pt.GetChanges()
+	if len(changes[0]) != 1 || changes[0][0].Page != pt.GetHomepage() || changes[0][0].Operation != UPDATE {
+		t.Errorf("Expected the homepage to be updated in the first wave, got %+v", changes[0])
+	}
+	if len(pt.GetDeletes()) != 0 {
+		t.Errorf("Expected the homepage not to be deleted")
+	}
+
+	yrs = []*YamlResource{
+		NewYamlResource("/_index.yml", createYamlNode("wiki", "")),
+		NewYamlResource("/docs.yml", createYamlNode("wiki", "Docs")),
+	}
+	homepage, rest = SplitHomepage(yrs, "")
+	if homepage == nil || homepage.Path != "/_index.yml" || len(rest) != 1 {
+		t.Errorf("Expected the root index file to be the homepage")
+	}
+
+	// an anchor page keeps its parent and its title when the resource has none
+	pt = NewPageTree(rest, "200")
+	pt.SetHomepage(homepage)
+	pt.SetHomepageRemote(&RemoteResource{Id: "200", Title: "Anchor", Ancestors: []Ancestor{{Id: "1", Title: "TEAM Home"}}})
+	if op := pt.GetHomepage().GetChangeType(); op != UPDATE {
+		t.Errorf("Expected the anchor page to be updated, got %v", op)
+	}
+	if title := pt.GetHomepage().GetTitle(); title != "Anchor" {
+		t.Errorf("Expected the anchor page to keep its title, got %s", title)
+	}
+}
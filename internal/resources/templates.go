@@ -3,20 +3,48 @@ package resources
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
+const (
+	WIKI_REPRESENTATION    = "wiki"
+	STORAGE_REPRESENTATION = "storage"
+	// markdown is converted to storage format before it is sent
+	MARKDOWN_REPRESENTATION = "markdown"
+	// Atlassian Document Format, the JSON document format of the cloud editor
+	ADF_REPRESENTATION = "atlas_doc_format"
+)
+
+var REPRESENTATIONS = []string{WIKI_REPRESENTATION, STORAGE_REPRESENTATION, MARKDOWN_REPRESENTATION, ADF_REPRESENTATION}
+
+// the representation of a template can be declared with a second extension, e.g. application.xhtml.mst
+var TEMPLATE_FORMATS = map[string]string{
+	".xhtml": STORAGE_REPRESENTATION,
+	".md":    MARKDOWN_REPRESENTATION,
+	".adf":   ADF_REPRESENTATION,
+}
+
 type TemplateProcessor struct {
 	templates map[string]Template
 }
 
 type Template struct {
-	Asset IAsset
-	Data  string
+	Asset          IAsset
+	Data           string
+	Representation string
 }
 
 func NewTemplateProcessor(templatesDir string) *TemplateProcessor {
+	templates, err := loadAllTemplates(templatesDir)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
 	tp := TemplateProcessor{
-		templates: loadAllTemplates(templatesDir),
+		templates: templates,
 	}
 
 	return &tp
@@ -36,6 +64,15 @@ func (tp TemplateProcessor) Get(kind string) (string, error) {
 	return template.Data, nil
 }
 
+// GetRepresentation returns the representation the template of kind renders to, wiki when undeclared
+func (tp TemplateProcessor) GetRepresentation(kind string) string {
+	if template, exists := tp.templates[kind]; exists {
+		return template.Representation
+	}
+
+	return WIKI_REPRESENTATION
+}
+
 func (tp TemplateProcessor) GetAll() []Template {
 	templates := []Template{}
 	for _, t := range tp.templates {
@@ -45,14 +82,44 @@ func (tp TemplateProcessor) GetAll() []Template {
 	return templates
 }
 
-func loadAllTemplates(templatesDir string) map[string]Template {
-	templates := map[string]Template{}
+func loadAllTemplates(templatesDir string) (map[string]Template, error) {
+	return indexTemplates(append(GetBuiltinTemplates(), LoadAssets(templatesDir, []string{".mst", ".mustache"}, false)...))
+}
 
-	assets := append(GetBuiltinTemplates(), LoadAssets(templatesDir, []string{".mst", ".mustache"}, false)...)
+// indexTemplates maps the templates by kind, a template of the templates dir replaces the builtin one of the same kind
+// whatever its representation, two templates of the templates dir for one kind (e.g. app.mst and app.xhtml.mst) are an error
+func indexTemplates(assets []IAsset) (map[string]Template, error) {
+	templates := map[string]Template{}
 
 	for _, asset := range assets {
-		templates[asset.GetName()] = Template{Asset: asset}
+		kind, representation := getTemplateFormat(asset.GetName())
+		if existing, exists := templates[kind]; exists && !existing.Asset.IsBuiltin() {
+			if asset.IsBuiltin() {
+				continue
+			}
+			return nil, errors.New(fmt.Sprintf("Both %s and %s are templates of kind '%s', remove one of them", existing.Asset.GetPath(), asset.GetPath(), kind))
+		}
+		templates[kind] = Template{Asset: asset, Representation: representation}
 	}
 
-	return templates
+	return templates, nil
+}
+
+func getTemplateFormat(name string) (string, string) {
+	ext := filepath.Ext(name)
+	if representation, exists := TEMPLATE_FORMATS[ext]; exists {
+		return strings.TrimSuffix(name, ext), representation
+	}
+
+	return name, WIKI_REPRESENTATION
+}
+
+func IsValidRepresentation(representation string) bool {
+	for _, r := range REPRESENTATIONS {
+		if r == representation {
+			return true
+		}
+	}
+
+	return false
 }
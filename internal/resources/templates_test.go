@@ -0,0 +1,38 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetTemplateFormat(t *testing.T) {
+	tests := map[string][2]string{
+		"application":       {"application", WIKI_REPRESENTATION},
+		"application.xhtml": {"application", STORAGE_REPRESENTATION},
+		"my.app":            {"my.app", WIKI_REPRESENTATION},
+	}
+
+	for name, expected := range tests {
+		kind, representation := getTemplateFormat(name)
+		if kind != expected[0] || representation != expected[1] {
+			t.Errorf("%s: expected %v, got [%s %s]", name, expected, kind, representation)
+		}
+	}
+}
+
+func TestIndexTemplates(t *testing.T) {
+	builtin := builtinAsset{name: "wiki", data: "{{content}}"}
+
+	templates, err := indexTemplates([]IAsset{builtin, Asset{name: "wiki.xhtml", path: "/templates/wiki.xhtml.mst"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templates["wiki"].Asset.IsBuiltin() || templates["wiki"].Representation != STORAGE_REPRESENTATION {
+		t.Errorf("Expected the template of the templates dir to replace the builtin one, got %+v", templates["wiki"])
+	}
+
+	_, err = indexTemplates([]IAsset{builtin, Asset{name: "app", path: "/templates/app.mst"}, Asset{name: "app.xhtml", path: "/templates/app.xhtml.mst"}})
+	if err == nil || !strings.Contains(err.Error(), "/templates/app.mst and /templates/app.xhtml.mst") {
+		t.Errorf("Expected two templates of one kind to be an error, got %v", err)
+	}
+}
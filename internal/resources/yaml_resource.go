@@ -3,7 +3,11 @@ package resources
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/aybabtme/orderedjson"
 	"github.com/mikefarah/yq/v4/pkg/yqlib"
@@ -18,6 +22,29 @@ type KindAndTitle struct {
 type Labels struct {
 	Labels []string `json:"labels"`
 }
+
+type Attachments struct {
+	Attachments []string `json:"attachments"`
+}
+
+type Representation struct {
+	Representation string `json:"representation"`
+}
+
+type ResourceId struct {
+	// Id is a string or a number, e.g. id: 42
+	Id json.RawMessage `json:"id"`
+}
+
+type Order struct {
+	Order      *int     `json:"order"`
+	Weight     *int     `json:"weight"`
+	ChildOrder []string `json:"childOrder"`
+}
+
+type EditorVersion struct {
+	EditorVersion string `json:"editorVersion"`
+}
 type YamlResource struct {
 	Kind  string
 	Title string
@@ -73,6 +100,111 @@ func (yr *YamlResource) GetLabels() []string {
 	return labels.Labels
 }
 
+// GetAttachments returns the file patterns of the attachments field, relative to GetSourceDir
+func (yr *YamlResource) GetAttachments() []string {
+	attachments := &Attachments{}
+	if err := json.Unmarshal([]byte(yr.Json), &attachments); err != nil {
+		panic(err)
+	}
+
+	return attachments.Attachments
+}
+
+// GetSourceDir returns the directory of the resource file, directory resources return their own path
+func (yr *YamlResource) GetSourceDir() string {
+	if IsResourceFile(yr.Path) {
+		return filepath.Dir(yr.Path)
+	}
+
+	return yr.Path
+}
+
+// GetRepresentation returns the representation declared by the resource, it overrides the one of the kind's template
+func (yr *YamlResource) GetRepresentation() string {
+	representation := &Representation{}
+	if err := json.Unmarshal([]byte(yr.Json), &representation); err != nil {
+		panic(err)
+	}
+
+	return representation.Representation
+}
+
+// GetResourceId returns the identity of the resource that is kept when it is moved or renamed, the id field
+// if present and the source path otherwise
+func (yr *YamlResource) GetResourceId() string {
+	id, err := yr.getResourceId()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if id == "" {
+		return yr.Path
+	}
+
+	return id
+}
+
+// getResourceId returns the id field as a string, numbers and booleans are converted to their YAML text
+func (yr *YamlResource) getResourceId() (string, error) {
+	resourceId := &ResourceId{}
+	if err := json.Unmarshal([]byte(yr.Json), &resourceId); err != nil {
+		panic(err)
+	}
+
+	raw := strings.TrimSpace(string(resourceId.Id))
+	switch {
+	case raw == "" || raw == "null":
+		return "", nil
+	case strings.HasPrefix(raw, `"`):
+		id := ""
+		err := json.Unmarshal([]byte(raw), &id)
+		return id, err
+	case strings.HasPrefix(raw, "{") || strings.HasPrefix(raw, "["):
+		return "", errors.New(fmt.Sprintf("Invalid id %s in %s, expected a string or a number", raw, yr.Path))
+	}
+
+	return raw, nil
+}
+
+// GetOrder returns the position of the page among its siblings, the order field or its weight alias. The second
+// value is false when the resource declares neither.
+func (yr *YamlResource) GetOrder() (int, bool) {
+	order := yr.getOrder()
+	if order.Order != nil {
+		return *order.Order, true
+	}
+	if order.Weight != nil {
+		return *order.Weight, true
+	}
+
+	return 0, false
+}
+
+// GetChildOrder returns the names of the children placed first, in this order, by the childOrder field of an index file
+func (yr *YamlResource) GetChildOrder() []string {
+	return yr.getOrder().ChildOrder
+}
+
+func (yr *YamlResource) getOrder() *Order {
+	order := &Order{}
+	if err := json.Unmarshal([]byte(yr.Json), &order); err != nil {
+		panic(err)
+	}
+
+	return order
+}
+
+// GetEditorVersion returns the editorVersion field, defaulted to the instance setting by the required-fields hook
+func (yr *YamlResource) GetEditorVersion() string {
+	editorVersion := &EditorVersion{}
+	if err := json.Unmarshal([]byte(yr.Json), &editorVersion); err != nil {
+		panic(err)
+	}
+
+	return editorVersion.EditorVersion
+}
+
 func (yr *YamlResource) ToObject() map[string]interface{} {
 	var obj map[string]interface{}
 
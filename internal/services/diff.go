@@ -0,0 +1,220 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
+	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
+	"github.com/fatih/color"
+)
+
+const DIFF_CONTEXT_LINES = 3
+
+type IDiffSrv interface {
+	Diff(string)
+}
+
+type DiffSrv struct{}
+
+func NewDiffService() DiffSrv {
+	return DiffSrv{}
+}
+
+func (DiffSrv) Diff(path string) {
+	dirProps := utils.GetDirectoryProperties(path)
+	config := confluence.LoadConfig(dirProps.ConfigPath)
+	api := confluence.NewConfluenceApi(dirProps.SpaceKey, config)
+
+	var pt *resources.PageTree
+	var changes [][]resources.PageUpdate
+
+	if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+		yr := resources.LoadYamlResourceChain(path)
+		pt, _ = loadPageTree(api, dirProps, yr, false)
+		changes = pt.GetChangesFor(yr[len(yr)-1].Path)
+	} else {
+		yr := resources.LoadYamlResources(dirProps.SpaceDir)
+		ensureUniqueResources(yr)
+		pt, _ = loadPageTree(api, dirProps, yr, false)
+		changes = pt.GetChanges()
+	}
+
+	differences := 0
+	for _, group := range changes {
+		for _, change := range group {
+			if change.Operation == resources.NOOP {
+				continue
+			}
+
+			diff, err := diffPage(api, pt, change)
+			if err != nil {
+				fmt.Printf("Failed to diff %s\n%s\n", pt.GetPagePath(change.Page), err.Error())
+				os.Exit(1)
+			}
+			printDiff(diff)
+			differences++
+		}
+	}
+
+	if differences == 0 {
+		color.New(color.FgHiBlack).Println("No differences. Confluence is up-to-date.")
+	}
+}
+
+func diffPage(api confluence.ConfluenceApi, pt *resources.PageTree, change resources.PageUpdate) (string, error) {
+	page := change.Page
+	path := pt.GetPagePath(page)
+	header := fmt.Sprintf("%s %s\n", strings.ToUpper(getPlanOperation(change)), path)
+
+	if change.Operation == resources.ORDER {
+		return header + diffOrder(pt, change), nil
+	}
+
+	remoteName := "/dev/null"
+	if page.Remote != nil {
+		remoteName = "remote:" + page.Remote.Link
+	}
+	localName := "/dev/null"
+	if page.Resource != nil {
+		localName = "local:" + path
+	}
+
+	remote := ""
+	if change.Operation == resources.DELETE || page.Sha256Differs() {
+		body, err := api.GetPageBody(page.GetRemoteId())
+		if err != nil {
+			return "", err
+		}
+		remote = formatStorage(body)
+	}
+
+	local := remote
+	if change.Operation == resources.CREATE || page.Sha256Differs() {
+		body := page.GetContent()
+		if page.GetRepresentation() != resources.STORAGE_REPRESENTATION {
+			var err error
+			body, err = api.ConvertToStorage(body, page.GetRepresentation())
+			if err != nil {
+				return "", err
+			}
+		}
+		local = formatStorage(body)
+	} else if change.Operation == resources.DELETE {
+		local = ""
+	}
+
+	return header + utils.UnifiedDiff(remoteName, localName, remote, local, DIFF_CONTEXT_LINES) + diffLocation(pt, change) + diffLabels(page) + diffAttachments(change), nil
+}
+
+// diffLocation shows the title path of moved and renamed pages before and after the upload
+func diffLocation(pt *resources.PageTree, change resources.PageUpdate) string {
+	if change.Operation != resources.MOVE && change.Operation != resources.RENAME {
+		return ""
+	}
+
+	return fmt.Sprintf("-location %s\n+location %s\n", pt.GetRemotePath(change.Page), "/"+strings.Join(change.Page.GetKeyArray(), "/"))
+}
+
+// diffOrder shows the children of an ordered page in their new order
+func diffOrder(pt *resources.PageTree, change resources.PageUpdate) string {
+	order := ""
+	for _, child := range change.Page.GetOrderedChildren() {
+		order += fmt.Sprintf("+order %s\n", pt.GetPagePath(child))
+	}
+
+	return order
+}
+
+func diffLabels(page *resources.Page) string {
+	local := map[string]bool{}
+	remote := map[string]bool{}
+
+	if page.Resource != nil {
+		for _, l := range append([]string{constants.GENERATED_BY_LABEL}, page.GetLabels()...) {
+			local[l] = true
+		}
+	}
+	if page.Remote != nil {
+		for _, l := range page.Remote.Labels {
+			remote[l] = true
+		}
+	}
+
+	lines := []string{}
+	for l := range remote {
+		if !local[l] {
+			lines = append(lines, "-label "+l)
+		}
+	}
+	for l := range local {
+		if !remote[l] {
+			lines = append(lines, "+label "+l)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i][1:] < lines[j][1:]
+	})
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func diffAttachments(change resources.PageUpdate) string {
+	if change.Operation == resources.DELETE {
+		return ""
+	}
+
+	lines := []string{}
+	for _, attachment := range change.Page.GetAttachmentChanges() {
+		switch attachment.Operation {
+		case resources.CREATE:
+			lines = append(lines, fmt.Sprintf("+attachment %s %s", attachment.Name, attachment.Local.Sha256))
+		case resources.UPDATE:
+			lines = append(lines, fmt.Sprintf("-attachment %s %s", attachment.Name, attachment.Remote.Sha256))
+			lines = append(lines, fmt.Sprintf("+attachment %s %s", attachment.Name, attachment.Local.Sha256))
+		case resources.DELETE:
+			lines = append(lines, fmt.Sprintf("-attachment %s %s", attachment.Name, attachment.Remote.Sha256))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// formatStorage puts every tag of a storage format body on its own line, so the diff can be read line by line
+func formatStorage(body string) string {
+	return strings.ReplaceAll(body, "><", ">\n<")
+}
+
+func printDiff(diff string) {
+	bold := color.New(color.Bold)
+	cyan := color.New(color.FgCyan)
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+
+	for i, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case i == 0, strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			bold.Println(line)
+		case strings.HasPrefix(line, "@@"):
+			cyan.Println(line)
+		case strings.HasPrefix(line, "+"):
+			green.Println(line)
+		case strings.HasPrefix(line, "-"):
+			red.Println(line)
+		default:
+			fmt.Println(line)
+		}
+	}
+	fmt.Println("")
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
+	"github.com/fatih/color"
+)
+
+// DRIFT_* select what upload does with pages that were changed in Confluence since y2c published them.
+// By default, the upload is refused when a drifted page would be updated.
+const (
+	DRIFT_OVERWRITE = "overwrite"
+	DRIFT_SKIP      = "skip"
+	DRIFT_PULL      = "pull"
+)
+
+type IDriftSrv interface {
+	Drift(string, bool)
+}
+
+type DriftSrv struct{}
+
+func NewDriftService() DriftSrv {
+	return DriftSrv{}
+}
+
+// DriftedPage is a managed page that was changed in Confluence after y2c published it
+type DriftedPage struct {
+	Title            string `json:"title"`
+	Path             string `json:"path"`
+	PageId           string `json:"pageId"`
+	Link             string `json:"link,omitempty"`
+	PublishedVersion int    `json:"publishedVersion,omitempty"`
+	Version          int    `json:"version"`
+	LastModifier     string `json:"lastModifier,omitempty"`
+	// Pulled is true when the Confluence body was written to the local resource
+	Pulled bool `json:"pulled,omitempty"`
+}
+
+func (DriftSrv) Drift(path string, asJson bool) {
+	dirProps := utils.GetDirectoryProperties(path)
+	config := confluence.LoadConfig(dirProps.ConfigPath)
+	api := confluence.NewConfluenceApi(dirProps.SpaceKey, config)
+
+	pt, _ := loadPageTree(api, dirProps, resources.LoadYamlResources(dirProps.SpaceDir), false)
+
+	drifted, err := findDrift(api, pt)
+	if err != nil {
+		fmt.Printf("Failed to detect drift in %s space\n%s\n", dirProps.SpaceKey, err.Error())
+		os.Exit(1)
+	}
+
+	if asJson {
+		data, err := json.MarshalIndent(drifted, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(drifted) == 0 {
+		color.New(color.FgHiBlack).Println("No drift. No page was changed in Confluence since it was published.")
+		return
+	}
+	printDriftedPages(drifted)
+}
+
+// findDrift returns the managed pages with a local resource that were changed in Confluence after y2c published them.
+// The homepage is included, an anchor page is usually written by hand before y2c publishes into it.
+func findDrift(api confluence.ConfluenceApi, pt *resources.PageTree) ([]DriftedPage, error) {
+	candidates := pt.GetPages()
+	if homepage := pt.GetHomepage(); homepage != nil {
+		candidates = append([]*resources.Page{homepage}, candidates...)
+	}
+
+	pages := []*resources.Page{}
+	user := ""
+	for _, page := range candidates {
+		if page.Resource == nil || page.Remote == nil {
+			continue
+		}
+		pages = append(pages, page)
+
+		if user == "" && page.Remote.PublishedVersion.Value == 0 {
+			var err error
+			if user, err = api.GetCurrentUser(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	drifted := []DriftedPage{}
+	for _, page := range pages {
+		if !page.Remote.IsEditedManually(user) {
+			continue
+		}
+
+		drifted = append(drifted, DriftedPage{
+			Title:            page.GetTitle(),
+			Path:             pt.GetPagePath(page),
+			PageId:           page.GetRemoteId(),
+			Link:             page.Remote.Link,
+			PublishedVersion: page.Remote.PublishedVersion.Value,
+			Version:          page.Remote.Version,
+			LastModifier:     page.Remote.LastModifier,
+		})
+	}
+
+	sort.SliceStable(drifted, func(i, j int) bool {
+		return drifted[i].Path < drifted[j].Path
+	})
+
+	return drifted, nil
+}
+
+// pullDrifted writes the Confluence body of drifted pages to their local resources and returns how many were pulled.
+// Pages that can't be pulled are reported and left unchanged.
+func pullDrifted(api confluence.ConfluenceApi, dirProps utils.DirectoryProperties, pt *resources.PageTree, drifted []DriftedPage, dryRun bool) int {
+	pulled := 0
+
+	for i, d := range drifted {
+		page := pt.GetPage(d.Path)
+		if homepage := pt.GetHomepage(); homepage != nil && homepage.GetRemoteId() == d.PageId {
+			page = homepage
+		}
+		if page == nil || page.Resource == nil {
+			continue
+		}
+
+		if !isOneOf(page.Resource.Kind, resources.VERBATIM_KINDS) {
+			fmt.Printf("Can't pull %s, pages of kind '%s' are rendered from a template\n", d.Path, page.Resource.Kind)
+			continue
+		}
+		if dryRun {
+			drifted[i].Pulled = true
+			pulled++
+			continue
+		}
+
+		body, err := api.GetPageBody(d.PageId)
+		if err == nil {
+			err = resources.PullStorageBody(dirProps.SpaceDir, page.Resource, body)
+		}
+		if err != nil {
+			fmt.Printf("Failed to pull %s\n%s\n", d.Path, err.Error())
+			continue
+		}
+
+		fmt.Printf("Pulled  %s\n", d.Path)
+		drifted[i].Pulled = true
+		pulled++
+	}
+
+	return pulled
+}
+
+// applyDriftPolicy changes the operations of drifted pages according to the policy. Without policy, drifted pages
+// are left as they are and true is returned when one of them would be updated.
+func applyDriftPolicy(changes [][]resources.PageUpdate, drifted []DriftedPage, policy string) bool {
+	byId := map[string]DriftedPage{}
+	for _, d := range drifted {
+		byId[d.PageId] = d
+	}
+
+	conflict := false
+	for _, group := range changes {
+		for i, change := range group {
+			d, exists := byId[change.Page.GetRemoteId()]
+			// ordering only moves the children of a page
+			if !exists || change.Operation == resources.DELETE || change.Operation == resources.ORDER {
+				continue
+			}
+
+			overwrite := policy == DRIFT_OVERWRITE || (policy == DRIFT_PULL && d.Pulled)
+			switch {
+			case policy == "":
+				conflict = conflict || change.Operation != resources.NOOP
+			case overwrite:
+				// unchanged resources are published again to replace the edits made in Confluence
+				if change.Operation == resources.NOOP {
+					group[i].Operation = resources.UPDATE
+				}
+			default:
+				group[i].Operation = resources.NOOP
+			}
+		}
+	}
+
+	return conflict
+}
+
+func printDriftedPages(drifted []DriftedPage) {
+	if len(drifted) == 0 {
+		return
+	}
+
+	gray := color.New(color.FgHiBlack)
+	color.New(color.FgYellow).Printf("%d pages were changed in Confluence since y2c published them\n", len(drifted))
+	for _, d := range drifted {
+		fmt.Printf("  %s\t%s\t%s\n", d.Title, d.Path, gray.Sprint(d.Link))
+		if d.PublishedVersion > 0 {
+			fmt.Printf("    published version %d, current version %d", d.PublishedVersion, d.Version)
+		} else {
+			fmt.Printf("    current version %d", d.Version)
+		}
+		if d.LastModifier != "" {
+			fmt.Printf(" by %s", d.LastModifier)
+		}
+		if d.Pulled {
+			fmt.Print(", pulled")
+		}
+		fmt.Println("")
+	}
+	fmt.Println("")
+}
+
+func isOneOf(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
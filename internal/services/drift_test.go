@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestResource(path string, title string) *resources.YamlResource {
+	node := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(fmt.Sprintf("kind: wiki\ntitle: %s", title)), &node); err != nil {
+		panic(err)
+	}
+
+	return resources.NewYamlResource(path, &node)
+}
+
+func TestApplyDriftPolicy(t *testing.T) {
+	ops := []resources.ChangeType{resources.NOOP, resources.UPDATE, resources.MOVE, resources.RENAME, resources.ORDER, resources.DELETE}
+	tests := []struct {
+		policy   string
+		pulled   bool
+		conflict bool
+		// expected is the operation of the drifted page for each of ops
+		expected []resources.ChangeType
+	}{
+		{"", false, true, []resources.ChangeType{resources.NOOP, resources.UPDATE, resources.MOVE, resources.RENAME, resources.ORDER, resources.DELETE}},
+		{DRIFT_OVERWRITE, false, false, []resources.ChangeType{resources.UPDATE, resources.UPDATE, resources.MOVE, resources.RENAME, resources.ORDER, resources.DELETE}},
+		{DRIFT_SKIP, false, false, []resources.ChangeType{resources.NOOP, resources.NOOP, resources.NOOP, resources.NOOP, resources.ORDER, resources.DELETE}},
+		{DRIFT_PULL, true, false, []resources.ChangeType{resources.UPDATE, resources.UPDATE, resources.MOVE, resources.RENAME, resources.ORDER, resources.DELETE}},
+		{DRIFT_PULL, false, false, []resources.ChangeType{resources.NOOP, resources.NOOP, resources.NOOP, resources.NOOP, resources.ORDER, resources.DELETE}},
+	}
+
+	for _, test := range tests {
+		for i, op := range ops {
+			drifted := &resources.Page{Remote: &resources.RemoteResource{Id: "10"}}
+			other := &resources.Page{Remote: &resources.RemoteResource{Id: "11"}}
+			changes := [][]resources.PageUpdate{{{Operation: op, Page: drifted}, {Operation: resources.UPDATE, Page: other}}}
+
+			conflict := applyDriftPolicy(changes, []DriftedPage{{PageId: "10", Pulled: test.pulled}}, test.policy)
+
+			expectedConflict := test.conflict && op != resources.NOOP && op != resources.ORDER && op != resources.DELETE
+			if conflict != expectedConflict {
+				t.Errorf("Policy '%s' (pulled %v) with operation %v: expected conflict %v, got %v", test.policy, test.pulled, op, expectedConflict, conflict)
+			}
+			if changes[0][0].Operation != test.expected[i] {
+				t.Errorf("Policy '%s' (pulled %v) with operation %v: expected operation %v, got %v", test.policy, test.pulled, op, test.expected[i], changes[0][0].Operation)
+			}
+			if changes[0][1].Operation != resources.UPDATE {
+				t.Errorf("Policy '%s': expected pages without drift to be left unchanged, got %v", test.policy, changes[0][1].Operation)
+			}
+		}
+	}
+}
+
+func TestFindDrift(t *testing.T) {
+	pt := resources.NewPageTree([]*resources.YamlResource{
+		newTestResource("/published.yml", "Published"),
+		newTestResource("/edited.yml", "Edited"),
+		newTestResource("/unrecorded.yml", "Unrecorded"),
+		newTestResource("/unrecorded-by-y2c.yml", "Unrecorded by y2c"),
+		newTestResource("/new.yml", "New"),
+	}, "")
+	published := resources.RemotePublishedVersion{Value: 2}
+	pt.GetPage("/published.yml").Remote = &resources.RemoteResource{Id: "10", Version: 2, PublishedVersion: published, LastModifier: "someone"}
+	pt.GetPage("/edited.yml").Remote = &resources.RemoteResource{Id: "11", Version: 3, PublishedVersion: published, LastModifier: "y2c"}
+	pt.GetPage("/unrecorded.yml").Remote = &resources.RemoteResource{Id: "12", Version: 5, LastModifier: "someone"}
+	pt.GetPage("/unrecorded-by-y2c.yml").Remote = &resources.RemoteResource{Id: "13", Version: 5, LastModifier: "y2c"}
+
+	api := &MockConfluenceApi{User: "y2c"}
+	drifted, err := findDrift(api, pt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(drifted) != 2 || drifted[0].PageId != "11" || drifted[1].PageId != "12" {
+		t.Fatalf("Expected the edited and unrecorded pages to be drifted, got %+v", drifted)
+	}
+	if drifted[0].Path != "/edited.yml" || drifted[0].PublishedVersion != 2 || drifted[0].Version != 3 {
+		t.Errorf("Unexpected drifted page %+v", drifted[0])
+	}
+	if len(api.Calls) != 1 {
+		t.Errorf("Expected the current user to be looked up once, got %v", api.Calls)
+	}
+}
+
+func TestAnchorPageDriftBlocksUpload(t *testing.T) {
+	pt := resources.NewPageTree([]*resources.YamlResource{newTestResource("/docs.yml", "Docs")}, "200")
+	pt.SetHomepage(newTestResource("/_index.yml", "Team"))
+	pt.SetHomepageRemote(&resources.RemoteResource{Id: "200", Title: "Team", Version: 3, LastModifier: "someone", Ancestors: []resources.Ancestor{{Id: "1", Title: "TEAM Home"}}})
+
+	drifted, err := findDrift(&MockConfluenceApi{User: "y2c"}, pt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 1 || drifted[0].PageId != "200" || drifted[0].Path != "/_index.yml" {
+		t.Fatalf("Expected the anchor page to be drifted, got %+v", drifted)
+	}
+
+	if !applyDriftPolicy(pt.GetChanges(), drifted, "") {
+		t.Errorf("Expected the upload to be refused when the anchor page was last modified by someone else")
+	}
+
+	changes := pt.GetChanges()
+	applyDriftPolicy(changes, drifted, DRIFT_SKIP)
+	for _, group := range changes {
+		for _, change := range group {
+			if change.Page == pt.GetHomepage() && change.Operation != resources.NOOP {
+				t.Errorf("Expected the anchor page to be skipped, got %v", change.Operation)
+			}
+		}
+	}
+}
+
+func TestOverwrittenDriftedPageIsUpdated(t *testing.T) {
+	pt := resources.NewPageTree([]*resources.YamlResource{newTestResource("/drifted.yml", "Drifted")}, "1")
+	page := pt.GetPage("/drifted.yml")
+	page.Remote = &resources.RemoteResource{Id: "10", Title: "Drifted", Version: 3, PublishedVersion: resources.RemotePublishedVersion{Value: 2}, Labels: []string{constants.GENERATED_BY_LABEL}}
+	if page.GetChangeType() != resources.NOOP {
+		t.Fatalf("Expected the unchanged resource to be a NOOP, got %v", page.GetChangeType())
+	}
+
+	changes := pt.GetChanges()
+	applyDriftPolicy(changes, []DriftedPage{{PageId: "10"}}, DRIFT_OVERWRITE)
+
+	api := &MockConfluenceApi{}
+	for _, group := range changes {
+		for _, change := range group {
+			if change.Page == page {
+				applyChange(api, pt, change)
+			}
+		}
+	}
+
+	if len(api.Calls) == 0 || fmt.Sprint(api.Calls[0]) != "[UpsertPage 10 true]" {
+		t.Errorf("Expected the drifted page to be updated in place, got %v", api.Calls)
+	}
+}
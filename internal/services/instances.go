@@ -1,18 +1,26 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/NorthfieldIT/yaml2confluence/internal/cli"
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
 	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
+	"github.com/fatih/color"
 	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 type IInstancesSrv interface {
 	New(string, bool)
-	List()
+	List(string)
+	Decrypt(string)
+	RotateSecret(string)
 }
 
 type InstancesSrv struct{}
@@ -25,19 +33,17 @@ func (InstancesSrv) New(baseDir string, configOnly bool) {
 	instance, instanceDir := cli.NewInstanceWizard(baseDir)
 	secret := utils.GetSecretAndGenerateIfMissing()
 
-	var authPointer *string
-	if instance.Type == "cloud" {
-		authPointer = &instance.API_token
-	} else {
-		authPointer = &instance.Password
+	for _, field := range instance.GetSecretFields() {
+		if *field.Value == "" {
+			continue
+		}
+		encrypted, err := utils.Encrypt(*field.Value, secret)
+		if err != nil {
+			panic(err)
+		}
+		*field.Value = encrypted
 	}
 
-	encryptedToken, err := utils.Encrypt(*authPointer, secret)
-	if err != nil {
-		panic(err)
-	}
-	*authPointer = "AES_ENC:" + encryptedToken
-
 	configYaml, err := yaml.Marshal(&instance)
 	if err != nil {
 		panic(err)
@@ -49,8 +55,212 @@ func (InstancesSrv) New(baseDir string, configOnly bool) {
 	}
 
 	utils.CreateInstanceDirectory(instanceDir, string(configYaml))
+	utils.RegisterInstance(instanceDir)
+}
+
+// List shows the instances from the registry and those found in baseDir, which may be an instance directory itself
+func (InstancesSrv) List(baseDir string) {
+	instanceDirs := findInstanceDirs(baseDir)
+	if len(instanceDirs) == 0 {
+		color.New(color.FgHiBlack).Println("-- none --")
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 3, 3, ' ', 0)
+	fmt.Fprintln(writer, color.New(color.Bold).Sprint("NAME\tTYPE\tHOST\tUSER\tSPACES\tDIRECTORY"))
+	for _, dir := range instanceDirs {
+		ic := confluence.ReadConfig(filepath.Join(dir, "config.yml"))
+		user := ic.User
+		if user == "" {
+			user = "<" + ic.GetAuthType() + ">"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", ic.Name, ic.Type, ic.Host, user, strings.Join(getSpaceDirs(dir), ","), dir)
+	}
+	writer.Flush()
+}
+
+func (InstancesSrv) Decrypt(configFile string) {
+	if !cli.Confirm("Secrets will be displayed in plain text, continue?") {
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		fmt.Printf("Could not read %s\n%s\n", configFile, err.Error())
+		os.Exit(1)
+	}
+
+	ic := confluence.ReadConfig(configFile)
+	if err := confluence.DecryptSecrets(&ic); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	// edit the document rather than marshalling the config, so the file is shown as written
+	doc := yamlv3.Node{}
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		panic(err)
+	}
+	for _, field := range ic.GetSecretFields() {
+		if node := utils.GetYamlNode(&doc, strings.Split(field.Key, ".")); node != nil {
+			node.Value = *field.Value
+		}
+	}
+
+	fmt.Println(strings.TrimSpace(string(utils.MarshalYamlNode(&doc))))
+}
+
+// RotateSecret re-encrypts the secrets of all known configs under a new secret,
+// values encrypted with the legacy AES-CFB scheme are migrated to AES-GCM
+func (InstancesSrv) RotateSecret(baseDir string) {
+	if os.Getenv("Y2C_SECRET") != "" {
+		fmt.Println("Y2C_SECRET is set, unset it to rotate the secret stored in " + utils.GetDefaultSecretPath())
+		os.Exit(1)
+	}
+
+	oldSecret, err := utils.GetSecret()
+	if err != nil {
+		fmt.Println("Could not find .secret")
+		os.Exit(1)
+	}
+	newSecret := utils.NewSecret()
+
+	// re-encrypt everything before writing, so a config that can't be decrypted leaves all files untouched
+	configs := map[string][]byte{}
+	for _, dir := range findInstanceDirs(baseDir) {
+		configFile := filepath.Join(dir, "config.yml")
+		data, err := reencryptConfig(configFile, oldSecret, newSecret)
+		if err != nil {
+			fmt.Printf("Could not re-encrypt %s\n%s\n", configFile, err.Error())
+			os.Exit(1)
+		}
+		if data != nil {
+			configs[configFile] = data
+		}
+	}
+
+	// without any encrypted config the old secret may still be needed by instances that aren't listed
+	if len(configs) == 0 {
+		fmt.Println("No config with encrypted values was found, pass the <base_dir> of the instances to re-encrypt, the secret was not rotated")
+		os.Exit(1)
+	}
+
+	backup := utils.GetDefaultSecretPath() + ".old"
+	if err := os.WriteFile(backup, []byte(oldSecret), 0600); err != nil {
+		fmt.Printf("Could not back up the secret to %s, the secret was not rotated\n%s\n", backup, err.Error())
+		os.Exit(1)
+	}
+	if err := utils.WriteSecret(newSecret); err != nil {
+		fmt.Printf("Could not write %s, the secret was not rotated\n%s\n", utils.GetDefaultSecretPath(), err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("Generated secret key: " + utils.GetDefaultSecretPath())
+
+	for configFile, data := range configs {
+		if err := writeConfig(configFile, data); err != nil {
+			fmt.Printf("Failed to write %s, the previous secret is kept in %s\n%s\n", configFile, backup, err.Error())
+			os.Exit(1)
+		}
+		fmt.Println("Re-encrypted " + configFile)
+	}
+
+	if err := os.Remove(backup); err != nil {
+		fmt.Printf("Could not remove the previous secret %s\n%s\n", backup, err.Error())
+		os.Exit(1)
+	}
+}
+
+// writeConfig replaces the config file, keeping its permissions
+func writeConfig(configFile string, data []byte) error {
+	info, err := os.Stat(configFile)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(configFile, data, info.Mode().Perm())
+}
+
+// reencryptConfig returns the updated config file, or nil if it holds no encrypted values
+func reencryptConfig(configFile, oldSecret, newSecret string) ([]byte, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := yamlv3.Node{}
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	ic := confluence.ReadConfig(configFile)
+	changed := false
+	for _, field := range ic.GetSecretFields() {
+		node := utils.GetYamlNode(&doc, strings.Split(field.Key, "."))
+		if node == nil || !utils.IsEncrypted(node.Value) {
+			continue
+		}
+
+		decrypted, err := utils.Decrypt(node.Value, oldSecret)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Could not decrypt %s, %s", field.Name, err.Error()))
+		}
+		node.Value, err = utils.Encrypt(decrypted, newSecret)
+		if err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil, nil
+	}
+
+	return utils.MarshalYamlNode(&doc), nil
 }
 
-func (InstancesSrv) List() {
-	fmt.Println("-- none --")
+func findInstanceDirs(baseDir string) []string {
+	candidates := utils.GetRegisteredInstances()
+
+	if baseDir != "" {
+		candidates = append(candidates, baseDir)
+		entries, err := os.ReadDir(baseDir)
+		if err != nil {
+			fmt.Printf("Could not read %s\n%s\n", baseDir, err.Error())
+			os.Exit(1)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				candidates = append(candidates, filepath.Join(baseDir, entry.Name()))
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	instanceDirs := []string{}
+	for _, dir := range candidates {
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		if _, err := os.Stat(filepath.Join(dir, "config.yml")); err == nil {
+			instanceDirs = append(instanceDirs, dir)
+		}
+	}
+
+	return instanceDirs
+}
+
+func getSpaceDirs(instanceDir string) []string {
+	spaces := []string{}
+
+	entries, _ := os.ReadDir(filepath.Join(instanceDir, "spaces"))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			spaces = append(spaces, entry.Name())
+		}
+	}
+
+	return spaces
 }
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
+	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+	"github.com/fatih/color"
+)
+
+// ProtectedDelete is a page without local resource that is kept, because deleting it would lose work done in Confluence
+type ProtectedDelete struct {
+	Title   string   `json:"title"`
+	Path    string   `json:"path"`
+	PageId  string   `json:"pageId"`
+	Link    string   `json:"link,omitempty"`
+	Reasons []string `json:"reasons"`
+}
+
+// protectDeletes removes the deletes of pages that were edited by hand after y2c published them, or that have child
+// pages not managed by y2c, from the page tree. The removed deletes are returned.
+func protectDeletes(api confluence.ConfluenceApi, pt *resources.PageTree) ([]ProtectedDelete, error) {
+	deletes := pt.GetDeletes()
+	if len(deletes) == 0 {
+		return nil, nil
+	}
+
+	// the author is only compared for pages published before their version was recorded
+	user := ""
+	for _, page := range deletes {
+		if page.Remote.PublishedVersion.Value == 0 {
+			var err error
+			if user, err = api.GetCurrentUser(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	ids := []string{}
+	reasons := map[string][]string{}
+	for _, page := range deletes {
+		ids = append(ids, page.GetRemoteId())
+		if page.Remote.IsEditedManually(user) {
+			reasons[page.GetRemoteId()] = append(reasons[page.GetRemoteId()], describeManualEdit(page.Remote))
+		}
+	}
+
+	descendants, err := api.GetDescendants(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	unmanaged := map[string][]string{}
+	for _, d := range descendants {
+		if len(d.Ancestors) == 0 || isManaged(d) {
+			continue
+		}
+		// grandchildren are reported by their own parent, when it is deleted as well
+		parentId := d.Ancestors[len(d.Ancestors)-1].Id
+		unmanaged[parentId] = append(unmanaged[parentId], d.Title)
+	}
+
+	protected := []ProtectedDelete{}
+	keep := map[string]bool{}
+	for _, page := range deletes {
+		id := page.GetRemoteId()
+		if children, exists := unmanaged[id]; exists {
+			sort.Strings(children)
+			reasons[id] = append(reasons[id], fmt.Sprintf("has child pages not managed by y2c: %s", strings.Join(children, ", ")))
+		}
+		if len(reasons[id]) == 0 {
+			continue
+		}
+
+		keep[id] = true
+		protected = append(protected, ProtectedDelete{
+			Title:   page.Remote.Title,
+			Path:    pt.GetPagePath(page),
+			PageId:  id,
+			Link:    page.Remote.Link,
+			Reasons: reasons[id],
+		})
+	}
+
+	sort.SliceStable(protected, func(i, j int) bool {
+		return protected[i].Path < protected[j].Path
+	})
+
+	pt.KeepDeletes(keep)
+
+	return protected, nil
+}
+
+func describeManualEdit(remote *resources.RemoteResource) string {
+	if remote.PublishedVersion.Value > 0 {
+		return fmt.Sprintf("edited in Confluence, y2c published version %d, the current version is %d", remote.PublishedVersion.Value, remote.Version)
+	}
+
+	return fmt.Sprintf("version %d was last modified by someone other than y2c", remote.Version)
+}
+
+func isManaged(page confluence.ConfluencePageExpanded) bool {
+	for _, label := range page.Metadata.Labels.Results {
+		if label.Name == constants.GENERATED_BY_LABEL {
+			return true
+		}
+	}
+
+	return false
+}
+
+func printProtectedDeletes(protected []ProtectedDelete) {
+	if len(protected) == 0 {
+		return
+	}
+
+	yellow := color.New(color.FgYellow)
+	yellow.Printf("Refusing to delete %d pages changed outside of y2c, use --force to delete them\n", len(protected))
+	for _, p := range protected {
+		fmt.Printf("  %s\t%s\t%s\n", p.Title, p.Path, color.New(color.FgHiBlack).Sprint(p.Link))
+		for _, reason := range p.Reasons {
+			fmt.Printf("    %s\n", reason)
+		}
+	}
+	fmt.Println("")
+}
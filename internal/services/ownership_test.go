@@ -0,0 +1,160 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
+	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+)
+
+// MockConfluenceApi implements the calls used by the planning of an upload, the others panic
+type MockConfluenceApi struct {
+	confluence.ConfluenceApi
+	User        string
+	Descendants []confluence.ConfluencePageExpanded
+	Settings    confluence.SpaceSettings
+	Server      bool
+	Calls       []interface{}
+	// uploads make calls in parallel
+	mu sync.Mutex
+}
+
+func (ma *MockConfluenceApi) IsServerInstance() bool {
+	return ma.Server
+}
+
+func (ma *MockConfluenceApi) GetCurrentUser() (string, error) {
+	ma.Calls = append(ma.Calls, []interface{}{"GetCurrentUser"})
+	return ma.User, nil
+}
+
+func (ma *MockConfluenceApi) GetDescendants(ids []string) ([]confluence.ConfluencePageExpanded, error) {
+	ma.Calls = append(ma.Calls, []interface{}{"GetDescendants", ids})
+	return ma.Descendants, nil
+}
+
+func (ma *MockConfluenceApi) GetSpaceSettings() (confluence.SpaceSettings, error) {
+	ma.Calls = append(ma.Calls, []interface{}{"GetSpaceSettings"})
+	return ma.Settings, nil
+}
+
+func (ma *MockConfluenceApi) UpsertPage(page confluence.UpsertPageContext) (string, string, error) {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	ma.Calls = append(ma.Calls, []interface{}{"UpsertPage", page.GetId(), page.IsUpdate()})
+
+	id := page.GetId()
+	if id == "" {
+		id = "new"
+	}
+	return id, "/pages/" + id, nil
+}
+
+func (ma *MockConfluenceApi) UpsertProperty(property confluence.UpsertPropertyContext) error {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	ma.Calls = append(ma.Calls, []interface{}{"UpsertProperty", property.GetId(), property.GetKey(), property.GetValue()})
+
+	return nil
+}
+
+func (ma *MockConfluenceApi) SetLabels(contentId string, labels []string) error {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	ma.Calls = append(ma.Calls, []interface{}{"SetLabels", contentId, labels})
+
+	return nil
+}
+
+func newDescendant(id string, title string, managed bool, ancestorIds ...string) confluence.ConfluencePageExpanded {
+	d := confluence.ConfluencePageExpanded{ConfluencePage: confluence.ConfluencePage{Id: id, Title: title}}
+	for _, ancestorId := range ancestorIds {
+		d.Ancestors = append(d.Ancestors, confluence.ConfluencePage{Id: ancestorId})
+	}
+	if managed {
+		d.Metadata.Labels.Results = []confluence.Label{{Prefix: "global", Name: constants.GENERATED_BY_LABEL}}
+	}
+
+	return d
+}
+
+func TestProtectDeletes(t *testing.T) {
+	top := []resources.Ancestor{{Id: "0", Title: "space"}, {Id: "1", Title: "anchor"}}
+	published := resources.RemotePublishedVersion{Value: 1}
+
+	pt := resources.NewPageTree([]*resources.YamlResource{}, "1")
+	pt.AddRemotes([]*resources.RemoteResource{
+		{Id: "10", Title: "parent", Version: 1, PublishedVersion: published, Ancestors: top},
+		{Id: "11", Title: "child", Version: 1, PublishedVersion: published, Ancestors: append(top, resources.Ancestor{Id: "10", Title: "parent"})},
+		{Id: "12", Title: "edited", Version: 2, PublishedVersion: published, Ancestors: top},
+		{Id: "13", Title: "unrecorded by someone", Version: 4, LastModifier: "someone", Ancestors: top},
+		{Id: "14", Title: "unrecorded by y2c", Version: 4, LastModifier: "y2c", Ancestors: top},
+		{Id: "15", Title: "with unmanaged child", Version: 1, PublishedVersion: published, Ancestors: top},
+	})
+
+	api := &MockConfluenceApi{User: "y2c", Descendants: []confluence.ConfluencePageExpanded{
+		newDescendant("11", "child", true, "0", "1", "10"),
+		// the unmanaged grandchild of 10 only protects its own parent, 10 is deleted and 11 keeps it
+		newDescendant("20", "notes", false, "0", "1", "10", "11"),
+		newDescendant("21", "meeting", false, "0", "1", "15"),
+		newDescendant("22", "draft", false, "0", "1", "15"),
+	}}
+
+	protected, err := protectDeletes(api, pt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reasons := map[string]string{}
+	for _, p := range protected {
+		reasons[p.PageId] = strings.Join(p.Reasons, "; ")
+	}
+	expected := map[string]string{
+		"11": "has child pages not managed by y2c: notes",
+		"12": "edited in Confluence, y2c published version 1, the current version is 2",
+		"13": "version 4 was last modified by someone other than y2c",
+		"15": "has child pages not managed by y2c: draft, meeting",
+	}
+	if len(reasons) != len(expected) {
+		t.Errorf("Expected %d protected pages, got %v", len(expected), reasons)
+	}
+	for id, reason := range expected {
+		if reasons[id] != reason {
+			t.Errorf("Expected page %s to be protected because it %s, got '%s'", id, reason, reasons[id])
+		}
+	}
+
+	deleted := []string{}
+	for _, page := range pt.GetDeletes() {
+		deleted = append(deleted, page.GetRemoteId())
+	}
+	sort.Strings(deleted)
+	if strings.Join(deleted, ",") != "10,14" {
+		t.Errorf("Expected only 10 and 14 to be deleted, got %v", deleted)
+	}
+}
+
+func TestProtectDeletesWithoutUnrecordedPages(t *testing.T) {
+	pt := resources.NewPageTree([]*resources.YamlResource{}, "1")
+	pt.AddRemotes([]*resources.RemoteResource{
+		{Id: "10", Title: "page", Version: 1, PublishedVersion: resources.RemotePublishedVersion{Value: 1}, Ancestors: []resources.Ancestor{{Id: "0", Title: "space"}, {Id: "1", Title: "anchor"}}},
+	})
+
+	api := &MockConfluenceApi{}
+	protected, err := protectDeletes(api, pt)
+	if err != nil || len(protected) != 0 {
+		t.Fatalf("Expected nothing to be protected, got %v %v", protected, err)
+	}
+	for _, call := range api.Calls {
+		if call.([]interface{})[0] == "GetCurrentUser" {
+			t.Errorf("Expected the current user only to be looked up for pages without a recorded version")
+		}
+	}
+	if len(pt.GetDeletes()) != 1 {
+		t.Errorf("Expected the page to be deleted")
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+	"github.com/fatih/color"
+)
+
+var PLAN_OPERATIONS = map[resources.ChangeType]string{
+	resources.CREATE: "create",
+	resources.UPDATE: "update",
+	resources.DELETE: "delete",
+	resources.NOOP:   "noop",
+	resources.MOVE:   "move",
+	resources.RENAME: "rename",
+	resources.ORDER:  "order",
+}
+
+const LABELS_ONLY_OPERATION = "labels"
+const ATTACHMENTS_ONLY_OPERATION = "attachments"
+
+var planColors = map[string]*color.Color{
+	"create":                   color.New(color.FgGreen),
+	"update":                   color.New(color.FgYellow),
+	LABELS_ONLY_OPERATION:      color.New(color.FgCyan),
+	ATTACHMENTS_ONLY_OPERATION: color.New(color.FgBlue),
+	"move":                     color.New(color.FgMagenta),
+	"rename":                   color.New(color.FgMagenta),
+	"order":                    color.New(color.FgCyan),
+	"delete":                   color.New(color.FgRed),
+	"noop":                     color.New(color.FgHiBlack),
+}
+
+type Plan struct {
+	Space       string         `json:"space"`
+	SpaceExists bool           `json:"spaceExists"`
+	Waves       []PlanWave     `json:"waves"`
+	Summary     map[string]int `json:"summary"`
+	// Protected are the pages that are not deleted because they were changed outside of y2c
+	Protected []ProtectedDelete `json:"protected,omitempty"`
+	// Drifted are the pages changed in Confluence since y2c published them
+	Drifted []DriftedPage `json:"drifted,omitempty"`
+	// SpaceChanges are the settings of space.yml that differ from the space
+	SpaceChanges []SpaceChange `json:"spaceChanges,omitempty"`
+}
+
+type PlanWave struct {
+	Wave    int         `json:"wave"`
+	Changes []PlanEntry `json:"changes"`
+}
+
+type PlanEntry struct {
+	Operation   string                 `json:"operation"`
+	Title       string                 `json:"title"`
+	Path        string                 `json:"path"`
+	PageId      string                 `json:"pageId,omitempty"`
+	Link        string                 `json:"link,omitempty"`
+	Attachments []PlanAttachmentChange `json:"attachments,omitempty"`
+	// From is the title path of moved and renamed pages in Confluence before the upload
+	From string `json:"from,omitempty"`
+	// Order is the paths of the children of an ordered page, in their new order
+	Order []string `json:"order,omitempty"`
+}
+
+type PlanAttachmentChange struct {
+	Operation string `json:"operation"`
+	Name      string `json:"name"`
+}
+
+func NewPlan(space string, spaceExists bool, pt *resources.PageTree, changes [][]resources.PageUpdate) Plan {
+	plan := Plan{
+		Space:       space,
+		SpaceExists: spaceExists,
+		Waves:       []PlanWave{},
+		Summary:     map[string]int{},
+	}
+
+	for _, op := range PLAN_OPERATIONS {
+		plan.Summary[op] = 0
+	}
+	plan.Summary[LABELS_ONLY_OPERATION] = 0
+	plan.Summary[ATTACHMENTS_ONLY_OPERATION] = 0
+
+	for _, group := range changes {
+		if len(group) == 0 {
+			continue
+		}
+
+		wave := PlanWave{Wave: len(plan.Waves) + 1}
+		for _, change := range group {
+			entry := newPlanEntry(pt, change)
+			plan.Summary[entry.Operation]++
+			wave.Changes = append(wave.Changes, entry)
+		}
+
+		plan.Waves = append(plan.Waves, wave)
+	}
+
+	return plan
+}
+
+func newPlanEntry(pt *resources.PageTree, change resources.PageUpdate) PlanEntry {
+	page := change.Page
+	entry := PlanEntry{
+		Operation: getPlanOperation(change),
+		Path:      pt.GetPagePath(page),
+		PageId:    page.GetRemoteId(),
+	}
+
+	if page.Resource != nil {
+		entry.Title = page.GetTitle()
+	} else if page.Remote != nil {
+		entry.Title = page.Remote.Title
+	}
+	if page.Remote != nil {
+		entry.Link = page.Remote.Link
+	}
+	if change.Operation == resources.MOVE || change.Operation == resources.RENAME {
+		entry.From = pt.GetRemotePath(page)
+	}
+	if change.Operation == resources.ORDER {
+		for _, child := range page.GetOrderedChildren() {
+			entry.Order = append(entry.Order, pt.GetPagePath(child))
+		}
+		return entry
+	}
+	if change.Operation != resources.DELETE && change.Operation != resources.NOOP {
+		for _, attachment := range page.GetAttachmentChanges() {
+			entry.Attachments = append(entry.Attachments, PlanAttachmentChange{PLAN_OPERATIONS[attachment.Operation], attachment.Name})
+		}
+	}
+
+	return entry
+}
+
+func getPlanOperation(change resources.PageUpdate) string {
+	if change.IsLabelsOnly() {
+		return LABELS_ONLY_OPERATION
+	}
+	if change.IsAttachmentsOnly() {
+		return ATTACHMENTS_ONLY_OPERATION
+	}
+
+	return PLAN_OPERATIONS[change.Operation]
+}
+
+func (p Plan) HasChanges() bool {
+	return len(p.SpaceChanges) > 0 || p.Summary["create"]+p.Summary["update"]+p.Summary[LABELS_ONLY_OPERATION]+p.Summary[ATTACHMENTS_ONLY_OPERATION]+p.Summary["move"]+p.Summary["rename"]+p.Summary["order"]+p.Summary["delete"] > 0
+}
+
+func (p Plan) PrintJson() {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(data))
+}
+
+func (p Plan) Print() {
+	bold := color.New(color.Bold)
+	gray := color.New(color.FgHiBlack)
+
+	bold.Printf("Plan for space %s\n", p.Space)
+	if !p.SpaceExists {
+		planColors["create"].Printf("Space %s does not exist and will be created\n", p.Space)
+	}
+	printSpaceChanges(p.SpaceChanges)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 3, 3, ' ', 0)
+	for _, wave := range p.Waves {
+		writer.Flush()
+		fmt.Println("")
+		bold.Printf("Wave %d\n", wave.Wave)
+
+		for _, entry := range wave.Changes {
+			link := entry.Link
+			if link == "" {
+				link = "-"
+			}
+			fmt.Fprintf(writer, "  %s\t%s\t%s\t%s\n", planColors[entry.Operation].Sprint(entry.Operation), entry.Title, entry.Path, gray.Sprint(link))
+			if entry.From != "" {
+				fmt.Fprintf(writer, "  \t  from %s\t\t\n", entry.From)
+			}
+			for i, path := range entry.Order {
+				fmt.Fprintf(writer, "  \t  %d\t%s\t\n", i+1, path)
+			}
+			for _, attachment := range entry.Attachments {
+				fmt.Fprintf(writer, "  \t  %s\t%s\t\n", planColors[attachment.Operation].Sprint(attachment.Operation), attachment.Name)
+			}
+		}
+	}
+	writer.Flush()
+
+	fmt.Println("")
+	printDriftedPages(p.Drifted)
+	printProtectedDeletes(p.Protected)
+	if !p.HasChanges() {
+		gray.Println("No changes. Confluence is up-to-date.")
+	}
+	printPlanSummary("Plan", p.Summary)
+}
+
+func printPlanSummary(name string, summary map[string]int) {
+	fmt.Printf("%s: %d to create, %d to update, %d labels only, %d attachments only, %d to move, %d to rename, %d to order, %d to delete, %d unchanged\n",
+		name, summary["create"], summary["update"], summary[LABELS_ONLY_OPERATION], summary[ATTACHMENTS_ONLY_OPERATION], summary["move"], summary["rename"], summary["order"], summary["delete"], summary["noop"])
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
+	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
+)
+
+type IPullSrv interface {
+	Pull(string, PullOptions)
+}
+
+type PullOptions struct {
+	// Anchor is the id of the page whose descendants are pulled, the space homepage by default
+	Anchor string
+	// Attachments downloads the attachments of the pages next to their resources
+	Attachments bool
+	// Overwrite replaces existing resource files
+	Overwrite bool
+	// Adopt labels the pulled pages as managed by y2c and records their current version as published, so upload
+	// updates them instead of creating new pages
+	Adopt bool
+}
+
+type PullSrv struct{}
+
+func NewPullService() PullSrv {
+	return PullSrv{}
+}
+
+func (PullSrv) Pull(spaceDirectory string, opts PullOptions) {
+	// the space directory of a space that isn't managed yet is created
+	if spaceDir := utils.ResolveAbsolutePathDir(spaceDirectory); strings.Contains(spaceDir, "spaces/") {
+		if err := os.MkdirAll(spaceDir, 0755); err != nil {
+			fmt.Printf("Failed to create space directory %s\n%s\n", spaceDir, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	dirProps := utils.GetDirectoryProperties(spaceDirectory)
+	config := confluence.LoadConfig(dirProps.ConfigPath)
+	api := confluence.NewConfluenceApi(dirProps.SpaceKey, config)
+
+	rootId := opts.Anchor
+	if rootId == "" {
+		exists, homepageId, err := api.GetSpace()
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		if !exists {
+			fmt.Printf("Space %s does not exist\n", dirProps.SpaceKey)
+			os.Exit(1)
+		}
+		rootId = homepageId
+	}
+
+	remotes, err := api.GetPagesWithBody(rootId)
+	if err != nil {
+		fmt.Printf("Failed to retrieve the pages of %s space\n%s\n", dirProps.SpaceKey, err.Error())
+		os.Exit(1)
+	}
+
+	pages := buildPulledTree(rootId, remotes)
+	byId := map[string]confluence.ConfluencePageExpanded{}
+	for _, remote := range remotes {
+		byId[remote.Id] = remote
+	}
+	resources.LayoutPulledPages(dirProps.SpaceDir, pages)
+
+	pulled := 0
+	for _, page := range flattenPulledPages(pages) {
+		path, _ := filepath.Rel(dirProps.SpaceDir, page.File)
+
+		if opts.Attachments {
+			if err := downloadAttachments(api, page); err != nil {
+				fmt.Printf("Failed to download the attachments of %s\n%s\n", path, err.Error())
+				os.Exit(1)
+			}
+		}
+
+		if err := resources.WritePulledPage(page, opts.Overwrite); err != nil {
+			fmt.Printf("Failed to pull %s\n%s\n", page.Title, err.Error())
+			os.Exit(1)
+		}
+
+		if opts.Adopt {
+			if err := adoptPage(api, dirProps.SpaceDir, page, byId[page.Id]); err != nil {
+				fmt.Printf("Failed to adopt %s as managed by y2c\n%s\n", page.Title, err.Error())
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("Pulled  %s\n", path)
+		pulled++
+	}
+
+	if opts.Anchor != "" {
+		if err := os.WriteFile(filepath.Join(dirProps.SpaceDir, ".anchor"), []byte(opts.Anchor), 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	fmt.Printf("Pulled %d pages into %s\n", pulled, dirProps.SpaceDir)
+	if !opts.Adopt && pulled > 0 {
+		fmt.Println("The pages are not managed by y2c yet, pull again with --adopt --overwrite before uploading to avoid duplicate titles")
+	}
+}
+
+// adoptPage labels the page as managed by y2c and records the current version as published from the pulled
+// resource, so the next upload neither reports the page as drifted nor publishes it from another resource
+func adoptPage(api confluence.ConfluenceApi, spaceDir string, pulled *resources.PulledPage, remote confluence.ConfluencePageExpanded) error {
+	if err := api.SetLabels(pulled.Id, []string{constants.GENERATED_BY_LABEL}); err != nil {
+		return err
+	}
+
+	page := &resources.Page{Resource: resources.LoadPulledResource(spaceDir, pulled), Remote: toRemoteResource([]confluence.ConfluencePageExpanded{remote}, "")[0]}
+	if err := api.UpsertProperty(page.GetPublishedVersionProperty(page.GetRemoteVersion())); err != nil {
+		return err
+	}
+
+	return api.UpsertProperty(page.GetResourceIdProperty())
+}
+
+// buildPulledTree arranges the pages below the root page by their parent, siblings are ordered by title
+func buildPulledTree(rootId string, remotes []confluence.ConfluencePageExpanded) []*resources.PulledPage {
+	byId := map[string]*resources.PulledPage{}
+	for _, remote := range remotes {
+		labels := []string{}
+		for _, label := range remote.Metadata.Labels.Results {
+			if label.Name != constants.GENERATED_BY_LABEL {
+				labels = append(labels, label.Name)
+			}
+		}
+
+		byId[remote.Id] = &resources.PulledPage{
+			Id:     remote.Id,
+			Title:  remote.Title,
+			Labels: labels,
+			Body:   remote.Body.Storage.Value,
+		}
+	}
+
+	roots := []*resources.PulledPage{}
+	for _, remote := range remotes {
+		if len(remote.Ancestors) == 0 {
+			continue
+		}
+
+		page := byId[remote.Id]
+		parentId := remote.Ancestors[len(remote.Ancestors)-1].Id
+		if parentId == rootId {
+			roots = append(roots, page)
+		} else if parent, exists := byId[parentId]; exists {
+			parent.Children = append(parent.Children, page)
+		}
+	}
+
+	sortPulledPages(roots)
+
+	return roots
+}
+
+func sortPulledPages(pages []*resources.PulledPage) {
+	sort.SliceStable(pages, func(i, j int) bool {
+		return pages[i].Title < pages[j].Title
+	})
+
+	for _, page := range pages {
+		sortPulledPages(page.Children)
+	}
+}
+
+func flattenPulledPages(pages []*resources.PulledPage) []*resources.PulledPage {
+	flat := []*resources.PulledPage{}
+	for _, page := range pages {
+		flat = append(flat, page)
+		flat = append(flat, flattenPulledPages(page.Children)...)
+	}
+
+	return flat
+}
+
+func downloadAttachments(api confluence.ConfluenceApi, page *resources.PulledPage) error {
+	attachments, base, err := api.GetAttachments(page.Id)
+	if err != nil || len(attachments) == 0 {
+		return err
+	}
+
+	if err := os.MkdirAll(page.AttachmentsDir, 0755); err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		data, err := api.DownloadAttachment(base + attachment.Links.Download)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Base(attachment.Title)
+		if err := os.WriteFile(filepath.Join(page.AttachmentsDir, name), data, 0644); err != nil {
+			return err
+		}
+		page.Attachments = append(page.Attachments, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+)
+
+func TestAdoptedPagesAreNotDrifted(t *testing.T) {
+	spaceDir := t.TempDir()
+	remotes := []confluence.ConfluencePageExpanded{
+		newDescendant("10", "Guides", false, "0", "1"),
+		newDescendant("11", "Setup", false, "0", "1", "10"),
+	}
+	for i := range remotes {
+		remotes[i].Version.Number = 7
+		remotes[i].Version.By = confluence.ConfluenceUser{AccountId: "someone"}
+		remotes[i].Body.Storage.Value = "<p>" + remotes[i].Title + "</p>"
+	}
+
+	pages := buildPulledTree("1", remotes)
+	resources.LayoutPulledPages(spaceDir, pages)
+
+	byId := map[string]confluence.ConfluencePageExpanded{}
+	for _, remote := range remotes {
+		byId[remote.Id] = remote
+	}
+
+	api := &MockConfluenceApi{User: "y2c"}
+	for _, page := range flattenPulledPages(pages) {
+		if err := resources.WritePulledPage(page, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := adoptPage(api, spaceDir, page, byId[page.Id]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the properties written by the adoption are read back by the next upload
+	properties := map[string]map[string]string{}
+	for _, call := range api.Calls {
+		if call := call.([]interface{}); call[0] == "UpsertProperty" {
+			id := call[1].(string)
+			if properties[id] == nil {
+				properties[id] = map[string]string{}
+			}
+			properties[id][call[2].(string)] = call[3].(string)
+		}
+	}
+	for i := range remotes {
+		remotes[i].Metadata.Properties.PublishedVersion.Value = properties[remotes[i].Id][resources.PUBLISHED_VERSION_PROPERTY]
+		remotes[i].Metadata.Properties.ResourceId.Value = properties[remotes[i].Id][resources.RESOURCE_ID_PROPERTY]
+	}
+	if properties["10"][resources.RESOURCE_ID_PROPERTY] != "/guides" || properties["11"][resources.RESOURCE_ID_PROPERTY] != "/guides/setup.yml" {
+		t.Errorf("Expected the resource ids of the pulled resources to be recorded, got %v", properties)
+	}
+
+	pt := resources.NewPageTree(resources.LoadYamlResources(spaceDir), "1")
+	pt.AddRemotes(toRemoteResource(remotes, ""))
+	for _, path := range []string{"/guides", "/guides/setup.yml"} {
+		if page := pt.GetPage(path); page == nil || page.Remote == nil {
+			t.Fatalf("Expected %s to be matched with its adopted page", path)
+		}
+	}
+
+	drifted, err := findDrift(api, pt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 0 {
+		t.Errorf("Expected adopted pages not to be drifted, got %+v", drifted)
+	}
+}
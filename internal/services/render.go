@@ -1,6 +1,7 @@
 package services
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -27,6 +28,11 @@ func (RenderSrv) RenderSingleResource(file string, output string) {
 	target := getRenderTarget(output)
 	rt.RenderTo(target, page)
 
+	if target == resources.ADF && page.Content.Representation != resources.ADF_REPRESENTATION {
+		fmt.Printf("%s renders to %s, not %s\n", file, page.GetRepresentation(), resources.ADF_REPRESENTATION)
+		os.Exit(1)
+	}
+
 	resources.PrettyPrint(target, page, os.Stdout)
 }
 
@@ -37,6 +43,8 @@ func getRenderTarget(output string) resources.RenderTarget {
 		return resources.JSON
 	case "yaml":
 		return resources.YAML
+	case "adf":
+		return resources.ADF
 	default:
 		return resources.MST
 	}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+)
+
+type UploadStatus string
+
+const (
+	SUCCEEDED UploadStatus = "succeeded"
+	FAILED    UploadStatus = "failed"
+	SKIPPED   UploadStatus = "skipped"
+)
+
+var REPORT_FORMATS = []string{"json", "junit"}
+
+type UploadReport struct {
+	Space    string         `json:"space"`
+	Started  time.Time      `json:"started"`
+	Duration float64        `json:"durationSeconds"`
+	Aborted  bool           `json:"aborted"`
+	Retries  int64          `json:"retries"`
+	Results  []UploadResult `json:"results"`
+	mu       sync.Mutex
+}
+
+type UploadResult struct {
+	Operation     string        `json:"operation"`
+	Status        UploadStatus  `json:"status"`
+	Title         string        `json:"title"`
+	Path          string        `json:"path"`
+	PageId        string        `json:"pageId,omitempty"`
+	VersionBefore int           `json:"versionBefore"`
+	VersionAfter  int           `json:"versionAfter"`
+	Link          string        `json:"link,omitempty"`
+	Duration      time.Duration `json:"-"`
+	Error         string        `json:"error,omitempty"`
+}
+
+func NewUploadReport(space string) *UploadReport {
+	return &UploadReport{
+		Space:   space,
+		Started: time.Now(),
+		Results: []UploadResult{},
+	}
+}
+
+func newUploadResult(pt *resources.PageTree, change resources.PageUpdate, status UploadStatus, err string) UploadResult {
+	entry := newPlanEntry(pt, change)
+	version := change.Page.GetRemoteVersion()
+
+	return UploadResult{
+		Operation:     entry.Operation,
+		Status:        status,
+		Title:         entry.Title,
+		Path:          entry.Path,
+		PageId:        entry.PageId,
+		VersionBefore: version,
+		VersionAfter:  version,
+		Link:          entry.Link,
+		Error:         err,
+	}
+}
+
+// Add records the result of a single change, it is safe to call concurrently
+func (r *UploadReport) Add(result UploadResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Results = append(r.Results, result)
+}
+
+func (r *UploadReport) Finish(aborted bool, retries int64) {
+	r.Aborted = aborted
+	r.Retries = retries
+	r.Duration = time.Since(r.Started).Seconds()
+}
+
+func (r *UploadReport) Count(status UploadStatus) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (r *UploadReport) Write(format, file string) error {
+	return writeReport(format, file, r, []*UploadReport{r})
+}
+
+// WriteReports writes the reports of several spaces to one file, as a JSON array or a JUnit test suite per space
+func WriteReports(reports []*UploadReport, format, file string) error {
+	return writeReport(format, file, reports, reports)
+}
+
+func writeReport(format, file string, value interface{}, reports []*UploadReport) error {
+	var data []byte
+	var err error
+
+	switch format {
+	case "json":
+		data, err = json.MarshalIndent(value, "", "  ")
+	case "junit":
+		data, err = toJUnit(reports)
+	default:
+		return errors.New(fmt.Sprintf("Unknown report format '%s', expected one of %v", format, REPORT_FORMATS))
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, data, 0644)
+}
+
+// MarshalJSON adds the duration of each result in seconds
+func (r UploadResult) MarshalJSON() ([]byte, error) {
+	type result UploadResult
+	return json.Marshal(struct {
+		result
+		Duration float64 `json:"durationSeconds"`
+	}{result(r), r.Duration.Seconds()})
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func toJUnit(reports []*UploadReport) ([]byte, error) {
+	suites := junitTestSuites{Name: "y2c upload"}
+	duration := 0.0
+
+	for _, r := range reports {
+		suite := r.toJUnitSuite()
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+		duration += r.Duration
+	}
+	suites.Time = junitSeconds(duration)
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (r *UploadReport) toJUnitSuite() junitTestSuite {
+	suite := junitTestSuite{
+		Name:      r.Space,
+		Tests:     len(r.Results),
+		Failures:  r.Count(FAILED),
+		Skipped:   r.Count(SKIPPED),
+		Time:      junitSeconds(r.Duration),
+		Timestamp: r.Started.Format(time.RFC3339),
+	}
+
+	for _, result := range r.Results {
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("%s %s", result.Operation, result.Path),
+			ClassName: r.Space,
+			Time:      junitSeconds(result.Duration.Seconds()),
+			SystemOut: result.Link,
+		}
+
+		switch result.Status {
+		case FAILED:
+			tc.Failure = &junitMessage{Message: fmt.Sprintf("Failed to %s %s", result.Operation, result.Title), Text: result.Error}
+		case SKIPPED:
+			tc.Skipped = &junitMessage{Message: result.Error}
+		}
+
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	return suite
+}
+
+func junitSeconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
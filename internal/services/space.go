@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
+	"github.com/fatih/color"
+)
+
+const (
+	SPACE_UPDATE = "update"
+	SPACE_GRANT  = "grant"
+	SPACE_REVOKE = "revoke"
+)
+
+// SPACE_ACCESS_PERMISSIONS are the Confluence permissions (operation and target) granted by each access of space.yml
+var SPACE_ACCESS_PERMISSIONS = map[string][]confluence.SpacePermission{
+	resources.ACCESS_VIEW: {
+		{Operation: "read", Target: "space"},
+	},
+	resources.ACCESS_EDIT: {
+		{Operation: "read", Target: "space"},
+		{Operation: "create", Target: "page"},
+		{Operation: "create", Target: "blogpost"},
+		{Operation: "create", Target: "comment"},
+		{Operation: "create", Target: "attachment"},
+	},
+	resources.ACCESS_ADMIN: {
+		{Operation: "read", Target: "space"},
+		{Operation: "administer", Target: "space"},
+	},
+}
+
+var SPACE_CHANGE_VERBS = map[string]string{
+	SPACE_UPDATE: "Updated",
+	SPACE_GRANT:  "Granted",
+	SPACE_REVOKE: "Revoked",
+}
+
+var spaceChangeColors = map[string]*color.Color{
+	SPACE_UPDATE: color.New(color.FgYellow),
+	SPACE_GRANT:  color.New(color.FgGreen),
+	SPACE_REVOKE: color.New(color.FgRed),
+}
+
+// SpaceChange is a difference between the space.yml of a space and the space in Confluence
+type SpaceChange struct {
+	Operation string `json:"operation"`
+	// Setting is name, description, type or permission
+	Setting string `json:"setting"`
+	Value   string `json:"value"`
+
+	space      confluence.SpaceContext
+	permission confluence.SpacePermission
+}
+
+// loadSpaceConfig reads the space.yml of a space, exiting when it is invalid
+func loadSpaceConfig(dirProps utils.DirectoryProperties) *resources.SpaceConfig {
+	config, err := resources.LoadSpaceConfig(dirProps.SpaceDir)
+	if err != nil {
+		fmt.Printf("Failed to load the configuration of %s space\n%s\n", dirProps.SpaceKey, err.Error())
+		os.Exit(1)
+	}
+
+	return config
+}
+
+// planSpaceChanges compares space.yml with the settings of the space. When the space doesn't exist yet, every
+// setting is a change. Only the permissions of the groups and users listed in space.yml are reconciled.
+func planSpaceChanges(api confluence.ConfluenceApi, config *resources.SpaceConfig, exists bool) ([]SpaceChange, error) {
+	changes := []SpaceChange{}
+
+	current := confluence.SpaceSettings{}
+	if exists {
+		var err error
+		if current, err = api.GetSpaceSettings(); err != nil {
+			return nil, err
+		}
+	}
+
+	// the type is set when the space is created, a different type of an existing space fails the upload before any
+	// other setting is applied
+	if !exists && config.Type != "" {
+		changes = append(changes, SpaceChange{Operation: SPACE_UPDATE, Setting: "type", Value: config.Type})
+	} else if config.Type != "" && config.GetConfluenceType() != current.Type {
+		changes = append(changes, SpaceChange{Operation: SPACE_UPDATE, Setting: "type", Value: fmt.Sprintf("%s (currently %s)", config.Type, current.Type)})
+	}
+
+	// the name is always written with the description, it keeps the current one unless space.yml sets it
+	space := *config
+	if space.Name == "" {
+		space.Name = current.Name
+	}
+	if config.Name != "" && config.Name != current.Name {
+		changes = append(changes, SpaceChange{Operation: SPACE_UPDATE, Setting: "name", Value: config.Name, space: &space})
+	}
+	if config.Description != "" && config.Description != current.Description {
+		changes = append(changes, SpaceChange{Operation: SPACE_UPDATE, Setting: "description", Value: config.Description, space: &space})
+	}
+
+	if len(config.Permissions) == 0 {
+		return changes, nil
+	}
+	if api.IsServerInstance() {
+		return nil, errors.New("Space permissions are only supported by Confluence Cloud")
+	}
+
+	desired := map[string]confluence.SpacePermission{}
+	subjects := map[string]bool{}
+	for _, permission := range config.Permissions {
+		subjects[permission.GetSubject()] = true
+		for _, access := range permission.Access {
+			for _, p := range SPACE_ACCESS_PERMISSIONS[access] {
+				p.SubjectType, p.Subject = permission.GetSubjectType(), permission.GetSubjectName()
+				desired[getPermissionKey(p)] = p
+			}
+		}
+	}
+
+	granted := map[string]bool{}
+	for _, p := range current.Permissions {
+		key := getPermissionKey(p)
+		granted[key] = true
+		if subjects[p.SubjectType+" "+p.Subject] && desired[key].Subject == "" {
+			changes = append(changes, newPermissionChange(SPACE_REVOKE, p))
+		}
+	}
+
+	keys := []string{}
+	for key := range desired {
+		if !granted[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		changes = append(changes, newPermissionChange(SPACE_GRANT, desired[key]))
+	}
+
+	return changes, nil
+}
+
+func newPermissionChange(operation string, p confluence.SpacePermission) SpaceChange {
+	return SpaceChange{Operation: operation, Setting: "permission", Value: getPermissionKey(p), permission: p}
+}
+
+func getPermissionKey(p confluence.SpacePermission) string {
+	return fmt.Sprintf("%s %s: %s %s", p.SubjectType, p.Subject, p.Operation, p.Target)
+}
+
+// applySpaceChanges updates the name and description of the space and grants and revokes its permissions
+func applySpaceChanges(api confluence.ConfluenceApi, spaceKey string, changes []SpaceChange) error {
+	updated := false
+	for _, change := range changes {
+		var err error
+		switch {
+		case change.Setting == "type":
+			err = errors.New(fmt.Sprintf("The type of an existing space can't be changed, remove type from %s or recreate the space", resources.SPACE_FILE))
+		case change.Operation == SPACE_UPDATE:
+			// a single update writes the name and the description
+			if !updated {
+				err = api.UpdateSpace(change.space)
+				updated = true
+			}
+		case change.Operation == SPACE_GRANT:
+			err = api.AddSpacePermission(change.permission)
+		case change.Operation == SPACE_REVOKE:
+			err = api.RemoveSpacePermission(change.permission)
+		}
+		if err != nil {
+			return errors.New(fmt.Sprintf("Failed to %s %s of %s space\n%s", change.Operation, change.Value, spaceKey, err.Error()))
+		}
+
+		fmt.Printf("%s  space %s %s\n", SPACE_CHANGE_VERBS[change.Operation], change.Setting, change.Value)
+	}
+
+	return nil
+}
+
+func printSpaceChanges(changes []SpaceChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	fmt.Println("")
+	color.New(color.Bold).Println("Space settings")
+	for _, change := range changes {
+		fmt.Printf("  %s\t%s %s\n", spaceChangeColors[change.Operation].Sprint(change.Operation), change.Setting, change.Value)
+	}
+}
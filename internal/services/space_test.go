@@ -0,0 +1,121 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+)
+
+func getChangeValues(changes []SpaceChange) map[string]bool {
+	values := map[string]bool{}
+	for _, change := range changes {
+		values[change.Operation+" "+change.Setting+" "+change.Value] = true
+	}
+
+	return values
+}
+
+func TestPlanSpaceChanges(t *testing.T) {
+	api := &MockConfluenceApi{Settings: confluence.SpaceSettings{
+		Name:        "Old Name",
+		Description: "Documentation of the team",
+		Type:        "global",
+		Permissions: []confluence.SpacePermission{
+			{Id: "1", SubjectType: "group", Subject: "developers", Operation: "read", Target: "space"},
+			{Id: "2", SubjectType: "group", Subject: "developers", Operation: "administer", Target: "space"},
+			{Id: "3", SubjectType: "group", Subject: "admins", Operation: "administer", Target: "space"},
+			{Id: "4", SubjectType: "user", Subject: "abc", Operation: "read", Target: "space"},
+		},
+	}}
+	config := &resources.SpaceConfig{
+		Name:        "Team Docs",
+		Description: "Documentation of the team",
+		Type:        resources.SPACE_TYPE_GLOBAL,
+		Permissions: []resources.SpacePermission{
+			{Group: "developers", Access: []string{resources.ACCESS_EDIT}},
+			{User: "abc", Access: []string{resources.ACCESS_VIEW}},
+		},
+	}
+
+	changes, err := planSpaceChanges(api, config, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"update name Team Docs",
+		"revoke permission group developers: administer space",
+		"grant permission group developers: create attachment",
+		"grant permission group developers: create blogpost",
+		"grant permission group developers: create comment",
+		"grant permission group developers: create page",
+	}
+	values := getChangeValues(changes)
+	if len(changes) != len(expected) {
+		t.Errorf("Expected %d changes, got %v", len(expected), values)
+	}
+	for _, value := range expected {
+		if !values[value] {
+			t.Errorf("Expected the change '%s', got %v", value, values)
+		}
+	}
+	// the admins group isn't listed in space.yml, its permissions are left untouched
+	for _, change := range changes {
+		if change.permission.Subject == "admins" {
+			t.Errorf("Expected the permissions of the admins group to be kept, got %v", change)
+		}
+	}
+	if changes[0].space.GetName() != "Team Docs" || changes[0].space.GetDescription() != "Documentation of the team" {
+		t.Errorf("Expected the update to write the name and the description of space.yml")
+	}
+}
+
+func TestPlanSpaceChangesKeepsUnsetSettings(t *testing.T) {
+	api := &MockConfluenceApi{Settings: confluence.SpaceSettings{Name: "Team Docs", Description: "Old", Type: "global"}}
+
+	changes, err := planSpaceChanges(api, &resources.SpaceConfig{Description: "New"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Setting != "description" || changes[0].space.GetName() != "Team Docs" {
+		t.Errorf("Expected only the description to be updated, keeping the current name, got %+v", changes)
+	}
+}
+
+func TestPlanSpaceChangesType(t *testing.T) {
+	api := &MockConfluenceApi{Settings: confluence.SpaceSettings{Name: "Team Docs", Type: "personal"}}
+
+	changes, err := planSpaceChanges(api, &resources.SpaceConfig{Type: resources.SPACE_TYPE_PRIVATE}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Setting != "type" || changes[0].Value != "private (currently personal)" {
+		t.Fatalf("Expected the type difference to be planned, got %+v", changes)
+	}
+	if err := applySpaceChanges(api, "TEAM", changes); err == nil {
+		t.Errorf("Expected the type of an existing space not to be changed")
+	}
+
+	api.Settings.Type = "global"
+	if changes, _ := planSpaceChanges(api, &resources.SpaceConfig{Type: resources.SPACE_TYPE_PRIVATE}, true); len(changes) != 0 {
+		t.Errorf("Expected a private space to be a global space, got %+v", changes)
+	}
+
+	changes, _ = planSpaceChanges(api, &resources.SpaceConfig{Name: "New Space", Type: resources.SPACE_TYPE_PRIVATE}, false)
+	if values := getChangeValues(changes); len(changes) != 2 || !values["update type private"] || !values["update name New Space"] {
+		t.Errorf("Expected every setting of a missing space to be a change, got %v", values)
+	}
+	if len(api.Calls) != 2 {
+		t.Errorf("Expected the settings of a missing space not to be read, got %v", api.Calls)
+	}
+}
+
+func TestPlanSpaceChangesPermissionsOnServer(t *testing.T) {
+	api := &MockConfluenceApi{Server: true}
+	config := &resources.SpaceConfig{Permissions: []resources.SpacePermission{{Group: "developers", Access: []string{resources.ACCESS_VIEW}}}}
+
+	if _, err := planSpaceChanges(api, config, true); err == nil {
+		t.Errorf("Expected space permissions to be rejected on server")
+	}
+}
@@ -1,9 +1,13 @@
 package services
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
 	"github.com/NorthfieldIT/yaml2confluence/internal/constants"
@@ -16,11 +20,41 @@ var CHANGE_VERBS = map[resources.ChangeType]string{
 	resources.UPDATE: "Updated",
 	resources.DELETE: "Deleted",
 	resources.NOOP:   "Skipped",
+	resources.MOVE:   "Moved  ",
+	resources.RENAME: "Renamed",
+	resources.ORDER:  "Ordered",
 }
 
+const FAILED_VERB = "Failed "
+const SKIPPED_VERB = "Skipped"
+
+// the number of changes applied at the same time
+const UPLOAD_CONCURRENCY = 10
+
 type IUploadSrv interface {
-	UploadSingleResource(string)
-	UploadSpace(string)
+	UploadSingleResource(string, UploadOptions)
+	UploadSpace(string, UploadOptions)
+	UploadInstance(string, UploadOptions)
+}
+
+type UploadOptions struct {
+	// DryRun computes and prints the change set without writing to Confluence
+	DryRun bool
+	// Json prints the dry run plan as JSON
+	Json bool
+	// ReportFormat is the format (json or junit) of the report written to ReportFile after the upload
+	ReportFormat string
+	ReportFile   string
+	// KeepGoing continues publishing after a failure instead of aborting the remaining waves
+	KeepGoing bool
+	// Force deletes pages that were changed outside of y2c
+	Force bool
+	// Drift is the DRIFT_* policy for pages changed in Confluence since they were published
+	Drift string
+	// Spaces is a pattern of the space keys uploaded by UploadInstance, all spaces when empty
+	Spaces string
+	// Parallel is the number of changes UploadInstance applies at the same time over all spaces
+	Parallel int
 }
 
 type UploadSrv struct {
@@ -31,29 +65,226 @@ func NewUploadService() UploadSrv {
 	return UploadSrv{NewRenderService()}
 }
 
-func (us UploadSrv) UploadSingleResource(file string) {
-	// dirProps := confluence.GetDirectoryProperties(file)
-	// title, markup := us.renderSrv.RenderSingleResource(file)
+func (us UploadSrv) UploadSingleResource(file string, opts UploadOptions) {
+	dirProps := utils.GetDirectoryProperties(file)
+	config := confluence.LoadConfig(dirProps.ConfigPath)
+	api := confluence.NewConfluenceApi(dirProps.SpaceKey, config)
+
+	yr := resources.LoadYamlResourceChain(file)
+
+	pt, spaceExisted := loadPageTree(api, dirProps, yr, !opts.DryRun)
+	pt, drifted := resolveDrift(api, dirProps, pt, opts, func() *resources.PageTree {
+		pt, _ := loadPageTree(api, dirProps, resources.LoadYamlResourceChain(file), !opts.DryRun)
+		return pt
+	})
+	su := &spaceUpload{
+		dirProps:     dirProps,
+		api:          api,
+		pt:           pt,
+		spaceExisted: spaceExisted,
+		changes:      pt.GetChangesFor(yr[len(yr)-1].Path),
+		drifted:      drifted,
+	}
 
-	// confluence.CreatePage(title, markup, dirProps.SpaceKey, confluence.LoadConfig(dirProps.ConfigPath))
+	publish(su, opts)
 }
 
-func (us UploadSrv) UploadSpace(spaceDirectory string) {
+func (us UploadSrv) UploadSpace(spaceDirectory string, opts UploadOptions) {
 	dirProps := utils.GetDirectoryProperties(spaceDirectory)
 	config := confluence.LoadConfig(dirProps.ConfigPath)
-	api := confluence.NewConfluenceApiService(dirProps.SpaceKey, config)
+
+	su := planSpaceUpload(resources.NewRenderTools(dirProps, true), dirProps, config, opts)
+
+	publish(su, opts)
+}
+
+// spaceUpload holds the changes planned for a space
+type spaceUpload struct {
+	dirProps     utils.DirectoryProperties
+	api          confluence.ConfluenceApi
+	pt           *resources.PageTree
+	spaceExisted bool
+	changes      [][]resources.PageUpdate
+	protected    []ProtectedDelete
+	drifted      []DriftedPage
+	// spaceChanges are the differences between space.yml and the settings of the space
+	spaceChanges []SpaceChange
+}
+
+// planSpaceUpload loads the resources of a space and computes the changes to publish them, the drift policy is
+// not applied yet
+func planSpaceUpload(rt *resources.RenderTools, dirProps utils.DirectoryProperties, config confluence.InstanceConfig, opts UploadOptions) *spaceUpload {
+	api := confluence.NewConfluenceApi(dirProps.SpaceKey, config)
 
 	yr := resources.LoadYamlResources(dirProps.SpaceDir)
+	ensureUniqueResources(yr)
+
+	pt, spaceExisted := loadPageTreeWith(api, rt, dirProps, yr, !opts.DryRun)
+	pt, drifted := resolveDrift(api, dirProps, pt, opts, func() *resources.PageTree {
+		pt, _ := loadPageTreeWith(api, rt, dirProps, resources.LoadYamlResources(dirProps.SpaceDir), !opts.DryRun)
+		return pt
+	})
 
+	protected := []ProtectedDelete{}
+	if !opts.Force {
+		var err error
+		if protected, err = protectDeletes(api, pt); err != nil {
+			fmt.Printf("Failed to check the pages to delete in %s space\n%s\n", dirProps.SpaceKey, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// in dry run mode, a missing space was not created and has no settings yet
+	spaceChanges, err := planSpaceChanges(api, loadSpaceConfig(dirProps), spaceExisted || !opts.DryRun)
+	if err != nil {
+		fmt.Printf("Failed to check the settings of %s space\n%s\n", dirProps.SpaceKey, err.Error())
+		os.Exit(1)
+	}
+
+	return &spaceUpload{
+		dirProps:     dirProps,
+		api:          api,
+		pt:           pt,
+		spaceExisted: spaceExisted,
+		changes:      pt.GetChanges(),
+		protected:    protected,
+		drifted:      drifted,
+		spaceChanges: spaceChanges,
+	}
+}
+
+// ensureUniqueResources exits when resources of a space share a title or an id
+func ensureUniqueResources(yr []*resources.YamlResource) {
 	if err := resources.EnsureUniqueTitles(yr); err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
+	if err := resources.EnsureUniqueResourceIds(yr); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
 
-	pt := resources.NewPageTree(yr, resources.GetAnchor(spaceDirectory))
+// resolveDrift finds the pages changed in Confluence since they were published. With the pull policy, the bodies
+// of those pages are written to their resources and the page tree is loaded again with reload.
+func resolveDrift(api confluence.ConfluenceApi, dirProps utils.DirectoryProperties, pt *resources.PageTree, opts UploadOptions, reload func() *resources.PageTree) (*resources.PageTree, []DriftedPage) {
+	drifted, err := findDrift(api, pt)
+	if err != nil {
+		fmt.Printf("Failed to detect drift in %s space\n%s\n", dirProps.SpaceKey, err.Error())
+		os.Exit(1)
+	}
+
+	if opts.Drift == DRIFT_PULL && pullDrifted(api, dirProps, pt, drifted, opts.DryRun) > 0 && !opts.DryRun {
+		pt = reload()
+	}
 
-	resources.NewRenderTools(dirProps, true).RenderAll(pt)
-	spaceExisted, id, err := api.CreateSpaceIfNotExists()
+	return pt, drifted
+}
+
+// publish applies the changes to Confluence, or only prints them when running in dry run mode
+func publish(su *spaceUpload, opts UploadOptions) {
+	conflict := applyDriftPolicy(su.changes, su.drifted, opts.Drift)
+
+	if opts.DryRun {
+		plan := su.getPlan()
+		if opts.Json {
+			plan.PrintJson()
+		} else {
+			plan.Print()
+		}
+		return
+	}
+
+	printDriftedPages(su.drifted)
+	if conflict {
+		fmt.Println("Upload refused, drifted pages would be overwritten. Use --overwrite, --skip-drifted or --pull")
+		os.Exit(1)
+	}
+
+	printProtectedDeletes(su.protected)
+
+	report, err := uploadChanges(su, opts, utils.NewSemaphore(UPLOAD_CONCURRENCY))
+
+	if report.Retries > 0 {
+		fmt.Printf("Retried %d requests\n", report.Retries)
+	}
+
+	if opts.ReportFormat != "" {
+		if werr := report.Write(opts.ReportFormat, opts.ReportFile); werr != nil {
+			fmt.Printf("Failed to write %s report %s\n%s\n", opts.ReportFormat, opts.ReportFile, werr.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s report %s\n", opts.ReportFormat, opts.ReportFile)
+	}
+
+	if err != nil {
+		if !opts.KeepGoing {
+			fmt.Println("Upload aborted, remaining changes were skipped")
+		}
+		fmt.Printf("%d succeeded, %d failed, %d skipped\n", report.Count(SUCCEEDED), report.Count(FAILED), report.Count(SKIPPED))
+		os.Exit(1)
+	}
+}
+
+// uploadChanges applies the changes and returns the report of the upload. At most budget changes are applied at
+// the same time, the budget can be shared by the uploads of several spaces.
+func uploadChanges(su *spaceUpload, opts UploadOptions, budget utils.Semaphore) (*UploadReport, error) {
+	report := NewUploadReport(su.dirProps.SpaceKey)
+
+	// the settings of the space are applied before its pages, a failure is reported like a failed page
+	if err := applySpaceChanges(su.api, su.dirProps.SpaceKey, su.spaceChanges); err != nil {
+		fmt.Printf("%s  %s\n%s\n", FAILED_VERB, resources.SPACE_FILE, err.Error())
+		report.Add(UploadResult{Operation: "space", Status: FAILED, Title: su.dirProps.SpaceKey, Path: resources.SPACE_FILE, Error: err.Error()})
+		if !opts.KeepGoing {
+			for _, group := range su.changes {
+				for _, change := range group {
+					report.Add(newUploadResult(su.pt, change, SKIPPED, "upload aborted"))
+				}
+			}
+			report.Finish(true, su.api.GetRetryCount())
+			return report, err
+		}
+	}
+
+	err := update(su.api, su.pt, su.changes, report, opts.KeepGoing, budget)
+	report.Finish(err != nil && !opts.KeepGoing, su.api.GetRetryCount())
+
+	return report, err
+}
+
+// getPlan returns the plan of the changes of the space
+func (su *spaceUpload) getPlan() Plan {
+	plan := NewPlan(su.dirProps.SpaceKey, su.spaceExisted, su.pt, su.changes)
+	plan.Protected = su.protected
+	plan.Drifted = su.drifted
+	plan.SpaceChanges = su.spaceChanges
+
+	return plan
+}
+
+// loadPageTree renders the resources into a page tree and attaches the pages y2c manages in Confluence.
+// When createSpace is false, nothing is written to Confluence and a missing space is reported as not existing.
+func loadPageTree(api confluence.ConfluenceApi, dirProps utils.DirectoryProperties, yr []*resources.YamlResource, createSpace bool) (*resources.PageTree, bool) {
+	return loadPageTreeWith(api, resources.NewRenderTools(dirProps, true), dirProps, yr, createSpace)
+}
+
+// loadPageTreeWith is loadPageTree rendering with rt, so the spaces of an instance can share templates and hooks
+func loadPageTreeWith(api confluence.ConfluenceApi, rt *resources.RenderTools, dirProps utils.DirectoryProperties, yr []*resources.YamlResource, createSpace bool) (*resources.PageTree, bool) {
+	spaceConfig := loadSpaceConfig(dirProps)
+	homepage, yr := resources.SplitHomepage(yr, spaceConfig.GetHomepagePath())
+
+	pt := resources.NewPageTree(yr, resources.GetAnchor(dirProps.SpaceDir))
+	if homepage != nil {
+		pt.SetHomepage(homepage)
+	}
+
+	rt.RenderAll(pt)
+
+	getSpace := api.GetSpace
+	if createSpace {
+		getSpace = func() (bool, string, error) { return api.CreateSpaceIfNotExists(spaceConfig) }
+	}
+	spaceExisted, id, err := getSpace()
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
@@ -72,7 +303,17 @@ func (us UploadSrv) UploadSpace(spaceDirectory string) {
 		pt.AddRemotes(toRemoteResource(pages, base))
 	}
 
-	update(api, pt.GetChanges())
+	// the homepage exists as soon as the space does, the anchor page has to exist already
+	if pt.GetHomepage() != nil && pt.HasAnchor() {
+		page, base, err := api.GetManagedPage(pt.GetAnchor())
+		if err != nil {
+			fmt.Printf("Failed to retrieve the homepage of %s space\n%s\n", dirProps.SpaceKey, err.Error())
+			os.Exit(1)
+		}
+		pt.SetHomepageRemote(toRemoteResource([]confluence.ConfluencePageExpanded{page}, base)[0])
+	}
+
+	return pt, spaceExisted
 }
 
 func toRemoteResource(pages []confluence.ConfluencePageExpanded, base string) []*resources.RemoteResource {
@@ -85,6 +326,9 @@ func toRemoteResource(pages []confluence.ConfluencePageExpanded, base string) []
 			ancestors = append(ancestors, resources.Ancestor{Id: ancestor.Id, Title: ancestor.Title})
 		}
 
+		// an unreadable version is treated as not recorded
+		publishedVersion, _ := strconv.Atoi(page.Metadata.Properties.PublishedVersion.Value)
+
 		labels := []string{}
 
 		for _, label := range page.Metadata.Labels.Results {
@@ -92,76 +336,263 @@ func toRemoteResource(pages []confluence.ConfluencePageExpanded, base string) []
 		}
 
 		remotes = append(remotes, &resources.RemoteResource{
-			Id:        page.Id,
-			Title:     page.Title,
-			Labels:    labels,
-			Link:      base + page.Links.Webui,
-			Version:   page.Version.Number,
-			Ancestors: ancestors,
+			Id:           page.Id,
+			Title:        page.Title,
+			Labels:       labels,
+			Link:         base + page.Links.Webui,
+			Version:      page.Version.Number,
+			Ancestors:    ancestors,
+			LastModifier: getUserId(page.Version.By),
 			Sha256: resources.RemoteSha256{
 				Id:      page.Metadata.Properties.Sha256.Id,
 				Value:   page.Metadata.Properties.Sha256.Value,
 				Version: page.Metadata.Properties.Sha256.Version.Number,
 			},
+			Attachments: resources.RemoteAttachments{
+				Id:      page.Metadata.Properties.Attachments.Id,
+				Value:   resources.ParseAttachmentsProperty(page.Metadata.Properties.Attachments.Value),
+				Version: page.Metadata.Properties.Attachments.Version.Number,
+			},
+			PublishedVersion: resources.RemotePublishedVersion{
+				Id:      page.Metadata.Properties.PublishedVersion.Id,
+				Value:   publishedVersion,
+				Version: page.Metadata.Properties.PublishedVersion.Version.Number,
+			},
+			ResourceId: resources.RemoteResourceId{
+				Id:      page.Metadata.Properties.ResourceId.Id,
+				Value:   page.Metadata.Properties.ResourceId.Value,
+				Version: page.Metadata.Properties.ResourceId.Version.Number,
+			},
+			Position: resources.RemotePosition{
+				Id:      page.Metadata.Properties.Position.Id,
+				Value:   page.Metadata.Properties.Position.Value,
+				Version: page.Metadata.Properties.Position.Version.Number,
+			},
 		})
 	}
 
 	return remotes
 }
 
-func update(api confluence.ConfluenceApiService, changes [][]resources.PageUpdate) error {
+// update applies the changes wave by wave, recording the outcome of every change in the report.
+// By default, the current wave is completed after a failure and the remaining waves are skipped.
+// With keepGoing, all waves run and only the descendants of pages that could not be created are skipped.
+func update(api confluence.ConfluenceApi, pt *resources.PageTree, changes [][]resources.PageUpdate, report *UploadReport, keepGoing bool, budget utils.Semaphore) error {
+	var failure error
+	var mu sync.Mutex
+	// pages that do not exist in Confluence because they failed to be created, or were skipped
+	missing := map[*resources.Page]bool{}
+
 	for _, group := range changes {
-		utils.EachLimit(len(group), 10, func(index int) {
+		if failure != nil && !keepGoing {
+			for _, change := range group {
+				report.Add(newUploadResult(pt, change, SKIPPED, "upload aborted"))
+			}
+			continue
+		}
+
+		utils.EachLimit(len(group), UPLOAD_CONCURRENCY, func(index int) {
 			change := group[index]
-			page := change.Page
 
-			switch change.Operation {
-			case resources.CREATE, resources.UPDATE:
-				id, link, err := api.UpsertPage(page)
-				if err != nil {
-					log.Fatal(err)
+			mu.Lock()
+			ancestor := getMissingAncestor(change.Page, missing)
+			if ancestor != nil {
+				missing[change.Page] = true
+			}
+			mu.Unlock()
+
+			if ancestor != nil {
+				reason := fmt.Sprintf("parent page %s does not exist", pt.GetPagePath(ancestor))
+				fmt.Printf("%s  %s\n%s\n", SKIPPED_VERB, pt.GetPagePath(change.Page), reason)
+				report.Add(newUploadResult(pt, change, SKIPPED, reason))
+				return
+			}
+
+			budget.Acquire()
+			result := applyChange(api, pt, change)
+			budget.Release()
+			report.Add(result)
+
+			if result.Status == FAILED {
+				mu.Lock()
+				if failure == nil {
+					failure = errors.New(result.Error)
 				}
-				if change.Operation == resources.CREATE {
-					page.Remote = &resources.RemoteResource{Id: id, Link: link}
+				if change.Operation == resources.CREATE && change.Page.GetRemoteId() == "" {
+					missing[change.Page] = true
 				}
+				mu.Unlock()
+			}
+		})
+	}
 
-				extraCalls := []func(){}
+	return failure
+}
 
-				if change.Operation == resources.CREATE || page.Sha256Differs() {
-					extraCalls = append(extraCalls, func() {
-						err = api.UpsertProperty(page.GetSha256Property())
-						if err != nil {
-							log.Fatal(err)
-						}
-					})
-				}
+func getMissingAncestor(page *resources.Page, missing map[*resources.Page]bool) *resources.Page {
+	for parent := page.GetParent(); parent != nil; parent = parent.GetParent() {
+		if missing[parent] {
+			return parent
+		}
+	}
 
-				if api.IsServerInstance() && (change.Operation == resources.CREATE || page.LabelsDiffer()) {
-					extraCalls = append(extraCalls, func() {
-						err = api.SetLabels(id, append([]string{constants.GENERATED_BY_LABEL}, page.GetLabels()...))
-						if err != nil {
-							log.Fatal(err)
-						}
-					})
-				}
+	return nil
+}
 
-				utils.EachLimit(len(extraCalls), 2, func(index int) { extraCalls[index]() })
+func applyChange(api confluence.ConfluenceApi, pt *resources.PageTree, change resources.PageUpdate) UploadResult {
+	page := change.Page
+	start := time.Now()
 
-				op := CHANGE_VERBS[change.Operation]
-				if change.Operation == resources.UPDATE && !page.Sha256Differs() && page.LabelsDiffer() {
-					op = "Labels "
-				}
-				fmt.Printf("%s  %s\n", op, page.Remote.Link)
-			case resources.DELETE:
-				err := api.DeletePage(page.GetRemoteId())
-				if err != nil {
-					log.Fatal(err)
+	result := newUploadResult(pt, change, SUCCEEDED, "")
+	fail := func(err error) UploadResult {
+		result.Status = FAILED
+		result.Error = err.Error()
+		result.Duration = time.Since(start)
+		fmt.Printf("%s  %s\n%s\n", FAILED_VERB, result.Path, strings.TrimSpace(err.Error()))
+		return result
+	}
+
+	switch change.Operation {
+	case resources.CREATE, resources.UPDATE, resources.MOVE, resources.RENAME:
+		id := page.GetRemoteId()
+		attachmentChanges := page.GetAttachmentChanges()
+		extraCalls := []func() error{}
+
+		// attachments are uploaded to the page, it doesn't need a new version when only they change
+		if change.IsAttachmentsOnly() {
+			result.VersionAfter = page.GetRemoteVersion()
+		} else {
+			result.VersionAfter = page.GetIncrementedVersion()
+
+			var link string
+			var err error
+			id, link, err = api.UpsertPage(page)
+			if err != nil {
+				return fail(err)
+			}
+			if change.Operation == resources.CREATE {
+				page.Remote = &resources.RemoteResource{Id: id, Title: page.GetTitle(), Link: link}
+				result.PageId = id
+				result.Link = link
+			}
+
+			// the published version tells later uploads whether the page was edited in Confluence
+			publishedVersion := page.GetPublishedVersionProperty(result.VersionAfter)
+			extraCalls = append(extraCalls, func() error {
+				return api.UpsertProperty(publishedVersion)
+			})
+		}
+
+		if len(attachmentChanges) > 0 {
+			extraCalls = append(extraCalls, func() error {
+				return syncAttachments(api, page, attachmentChanges)
+			})
+		}
+
+		if change.Operation == resources.CREATE || page.Sha256Differs() {
+			extraCalls = append(extraCalls, func() error {
+				return api.UpsertProperty(page.GetSha256Property())
+			})
+		}
+
+		if change.Operation == resources.CREATE || page.ResourceIdDiffers() {
+			extraCalls = append(extraCalls, func() error {
+				return api.UpsertProperty(page.GetResourceIdProperty())
+			})
+		}
+
+		if api.IsServerInstance() && (change.Operation == resources.CREATE || page.LabelsDiffer()) {
+			extraCalls = append(extraCalls, func() error {
+				return api.SetLabels(id, append([]string{constants.GENERATED_BY_LABEL}, page.GetLabels()...))
+			})
+		}
+
+		errs := make([]error, len(extraCalls))
+		utils.EachLimit(len(extraCalls), 2, func(index int) { errs[index] = extraCalls[index]() })
+		for _, err := range errs {
+			if err != nil {
+				return fail(err)
+			}
+		}
+
+		op := CHANGE_VERBS[change.Operation]
+		if change.IsLabelsOnly() {
+			op = "Labels "
+		} else if change.IsAttachmentsOnly() {
+			op = "Synced "
+		}
+		fmt.Printf("%s  %s\n", op, page.Remote.Link)
+	case resources.ORDER:
+		for _, position := range page.GetPositionChanges() {
+			// children that failed to be created are left out
+			if position.Page.GetRemoteId() == "" || (position.Target != nil && position.Target.GetRemoteId() == "") {
+				continue
+			}
+			if position.Target != nil {
+				if err := api.MovePage(position.Page.GetRemoteId(), position.Position, position.Target.GetRemoteId()); err != nil {
+					return fail(err)
 				}
-				fmt.Printf("%s  %s\n", CHANGE_VERBS[change.Operation], page.Remote.Link)
-			case resources.NOOP:
-				fmt.Printf("%s  %s\n", CHANGE_VERBS[change.Operation], page.Remote.Link)
 			}
-		})
+			if err := api.UpsertProperty(position.Page.GetPositionProperty(position.Previous)); err != nil {
+				return fail(err)
+			}
+		}
+		fmt.Printf("%s  %s\n", CHANGE_VERBS[change.Operation], result.Path)
+	case resources.DELETE:
+		err := api.DeletePage(page.GetRemoteId())
+		if err != nil {
+			return fail(err)
+		}
+		result.VersionAfter = 0
+		fmt.Printf("%s  %s\n", CHANGE_VERBS[change.Operation], page.Remote.Link)
+	case resources.NOOP:
+		// pages published before resource ids were recorded get one, so they can be moved later
+		if page.ResourceIdDiffers() {
+			if err := api.UpsertProperty(page.GetResourceIdProperty()); err != nil {
+				return fail(err)
+			}
+		}
+		fmt.Printf("%s  %s\n", CHANGE_VERBS[change.Operation], page.Remote.Link)
 	}
-	return nil
+
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+// syncAttachments uploads new and changed attachments, deletes the ones no longer declared and records them
+// in the attachments property of the page
+func syncAttachments(api confluence.ConfluenceApi, page *resources.Page, changes []resources.AttachmentChange) error {
+	attachments := map[string]resources.RemoteAttachment{}
+	if page.Remote != nil {
+		for name, remote := range page.Remote.Attachments.Value {
+			attachments[name] = remote
+		}
+	}
+
+	for _, change := range changes {
+		switch change.Operation {
+		case resources.CREATE, resources.UPDATE:
+			id, err := api.UpsertAttachment(page.GetRemoteId(), change.Local.Path)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Failed to upload attachment %s\n%s", change.Name, err.Error()))
+			}
+			attachments[change.Name] = resources.RemoteAttachment{Id: id, Sha256: change.Local.Sha256}
+		case resources.DELETE:
+			if err := api.DeleteAttachment(change.Remote.Id); err != nil {
+				return errors.New(fmt.Sprintf("Failed to delete attachment %s\n%s", change.Name, err.Error()))
+			}
+			delete(attachments, change.Name)
+		}
+	}
+
+	return api.UpsertProperty(page.GetAttachmentsProperty(attachments))
+}
+
+func getUserId(user confluence.ConfluenceUser) string {
+	if user.AccountId != "" {
+		return user.AccountId
+	}
+
+	return user.Username
 }
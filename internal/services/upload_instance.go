@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/NorthfieldIT/yaml2confluence/internal/confluence"
+	"github.com/NorthfieldIT/yaml2confluence/internal/resources"
+	"github.com/NorthfieldIT/yaml2confluence/internal/utils"
+	"github.com/fatih/color"
+)
+
+// InstancePlan is the plan of an upload of several spaces
+type InstancePlan struct {
+	Spaces  []Plan         `json:"spaces"`
+	Summary map[string]int `json:"summary"`
+}
+
+// UploadInstance uploads the spaces of an instance directory. The spaces are planned one after the other with
+// shared templates and hooks, then uploaded in parallel. Nothing is published when a space can't be planned.
+func (us UploadSrv) UploadInstance(instanceDirectory string, opts UploadOptions) {
+	spaceDirs, err := utils.GetSpaceDirectories(instanceDirectory, opts.Spaces)
+	if err != nil {
+		fmt.Printf("Failed to list the spaces of %s\n%s\n", instanceDirectory, err.Error())
+		os.Exit(1)
+	}
+	if len(spaceDirs) == 0 {
+		fmt.Printf("No space of %s matches '%s'\n", instanceDirectory, opts.Spaces)
+		os.Exit(1)
+	}
+
+	var rt *resources.RenderTools
+	var config confluence.InstanceConfig
+	uploads := []*spaceUpload{}
+	conflicts := []string{}
+
+	for _, spaceDir := range spaceDirs {
+		dirProps := utils.GetDirectoryProperties(spaceDir)
+		if rt == nil {
+			rt = resources.NewRenderTools(dirProps, true)
+			config = confluence.LoadConfig(dirProps.ConfigPath)
+		}
+
+		su := planSpaceUpload(rt.ForSpace(dirProps), dirProps, config, opts)
+		if applyDriftPolicy(su.changes, su.drifted, opts.Drift) {
+			conflicts = append(conflicts, dirProps.SpaceKey)
+		}
+		uploads = append(uploads, su)
+	}
+
+	if opts.DryRun {
+		printInstancePlan(uploads, opts.Json)
+		return
+	}
+
+	for _, su := range uploads {
+		printDriftedPages(su.drifted)
+		printProtectedDeletes(su.protected)
+	}
+	if len(conflicts) > 0 {
+		fmt.Printf("Upload refused, drifted pages of %v would be overwritten. Use --overwrite, --skip-drifted or --pull\n", conflicts)
+		os.Exit(1)
+	}
+
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = UPLOAD_CONCURRENCY
+	}
+	budget := utils.NewSemaphore(parallel)
+
+	reports := make([]*UploadReport, len(uploads))
+	errs := make([]error, len(uploads))
+	utils.EachLimit(len(uploads), len(uploads), func(index int) {
+		su := uploads[index]
+		reports[index], errs[index] = uploadChanges(su, opts, budget)
+	})
+
+	if opts.ReportFormat != "" {
+		if werr := WriteReports(reports, opts.ReportFormat, opts.ReportFile); werr != nil {
+			fmt.Printf("Failed to write %s report %s\n%s\n", opts.ReportFormat, opts.ReportFile, werr.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s report %s\n", opts.ReportFormat, opts.ReportFile)
+	}
+
+	if !printInstanceReport(reports, errs, opts.KeepGoing) {
+		os.Exit(1)
+	}
+}
+
+func printInstancePlan(uploads []*spaceUpload, asJson bool) {
+	instancePlan := InstancePlan{Spaces: []Plan{}, Summary: map[string]int{}}
+	for _, su := range uploads {
+		plan := su.getPlan()
+		instancePlan.Spaces = append(instancePlan.Spaces, plan)
+
+		for op, count := range plan.Summary {
+			instancePlan.Summary[op] += count
+		}
+	}
+
+	if asJson {
+		data, err := json.MarshalIndent(instancePlan, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	for _, plan := range instancePlan.Spaces {
+		plan.Print()
+		fmt.Println("")
+	}
+	color.New(color.Bold).Printf("Plan for %d spaces\n", len(instancePlan.Spaces))
+	printPlanSummary("Total", instancePlan.Summary)
+}
+
+// printInstanceReport prints the outcome of the upload of every space and returns true when all of them succeeded
+func printInstanceReport(reports []*UploadReport, errs []error, keepGoing bool) bool {
+	succeeded := true
+	retries := int64(0)
+	totals := map[UploadStatus]int{}
+
+	fmt.Println("")
+	for i, report := range reports {
+		status := ""
+		if errs[i] != nil {
+			succeeded = false
+			if !keepGoing {
+				status = ", aborted"
+			}
+		}
+		fmt.Printf("%s: %d succeeded, %d failed, %d skipped%s\n", report.Space, report.Count(SUCCEEDED), report.Count(FAILED), report.Count(SKIPPED), status)
+
+		for _, s := range []UploadStatus{SUCCEEDED, FAILED, SKIPPED} {
+			totals[s] += report.Count(s)
+		}
+		retries += report.Retries
+	}
+
+	if retries > 0 {
+		fmt.Printf("Retried %d requests\n", retries)
+	}
+	fmt.Printf("Total for %d spaces: %d succeeded, %d failed, %d skipped\n", len(reports), totals[SUCCEEDED], totals[FAILED], totals[SKIPPED])
+
+	return succeeded
+}
@@ -39,3 +39,18 @@ func EachLimit(length, limit int, work func(int)) {
 	close(ch) // This tells the goroutines there's nothing else to do
 	wg.Wait() // Wait for the threads to finish
 }
+
+// Semaphore bounds the amount of work done at the same time, it can be shared by several work pools
+type Semaphore chan struct{}
+
+func NewSemaphore(limit int) Semaphore {
+	return make(Semaphore, limit)
+}
+
+func (s Semaphore) Acquire() {
+	s <- struct{}{}
+}
+
+func (s Semaphore) Release() {
+	<-s
+}
@@ -33,7 +33,13 @@ func GetDefaultSecretPath() string {
 	return filepath.Join(GetDefaultY2cHomeDir(), DEFAULT_SECRET_FILENAME)
 }
 
+// GetSecret returns the secret used to encrypt credentials, the Y2C_SECRET environment variable
+// takes precedence over the secret file so it can be provided in CI
 func GetSecret() (string, error) {
+	if secret := os.Getenv("Y2C_SECRET"); secret != "" {
+		return secret, nil
+	}
+
 	secret, err := os.ReadFile(GetDefaultSecretPath())
 	if err != nil {
 		return "", err
@@ -52,23 +58,27 @@ func GetSecretAndGenerateIfMissing() string {
 }
 
 func GenerateSecret() string {
-	secret := []byte(randstr.String(24))
-
-	dir := GetDefaultY2cHomeDir()
-	secretPath := GetDefaultSecretPath()
-	err := os.MkdirAll(dir, os.ModePerm)
-	if err != nil {
+	secret := NewSecret()
+	if err := WriteSecret(secret); err != nil {
 		panic(err)
 	}
 
-	err = os.WriteFile(secretPath, secret, 0600)
+	fmt.Println("Generated secret key: " + GetDefaultSecretPath())
+
+	return secret
+}
+
+func NewSecret() string {
+	return randstr.String(24)
+}
+
+func WriteSecret(secret string) error {
+	err := os.MkdirAll(GetDefaultY2cHomeDir(), os.ModePerm)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	fmt.Println("Generated secret key: " + secretPath)
-
-	return string(secret)
+	return os.WriteFile(GetDefaultSecretPath(), []byte(secret), 0600)
 }
 
 func GetDirectoryProperties(path string) DirectoryProperties {
@@ -115,6 +125,45 @@ func GetDirectoryProperties(path string) DirectoryProperties {
 	return props
 }
 
+// IsInstanceDirectory reports whether dir holds the config.yml and spaces directory of an instance
+func IsInstanceDirectory(dir string) bool {
+	dir = ResolveAbsolutePathDir(dir)
+	if _, err := os.Stat(filepath.Join(dir, "config.yml")); err != nil {
+		return false
+	}
+	stat, err := os.Stat(filepath.Join(dir, "spaces"))
+
+	return err == nil && stat.IsDir()
+}
+
+// GetSpaceDirectories returns the space directories of an instance whose space key matches pattern, sorted by key.
+// An empty pattern matches all spaces.
+func GetSpaceDirectories(instanceDir string, pattern string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(ResolveAbsolutePathDir(instanceDir), "spaces"))
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if pattern != "" {
+			matched, err := filepath.Match(pattern, entry.Name())
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		dirs = append(dirs, filepath.Join(ResolveAbsolutePathDir(instanceDir), "spaces", entry.Name()))
+	}
+
+	return dirs, nil
+}
+
 func CreateInstanceDirectory(instanceDir string, config string) {
 	configFile := filepath.Join(instanceDir, "config.yml")
 
@@ -1,5 +1,12 @@
 package utils
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
 type MockFileSystem struct {
 	wd string
 }
@@ -15,3 +22,28 @@ func (mfs MockFileSystem) Getwd() (string, error) { return mfs.wd, nil }
 // 	t.Fatal("TEST " + test)
 // 	fmt.Println(path)
 // }
+
+func TestGetSpaceDirectories(t *testing.T) {
+	instanceDir := t.TempDir()
+	for _, key := range []string{"DOC", "DOCS", "OPS", ".hidden"} {
+		os.MkdirAll(filepath.Join(instanceDir, "spaces", key), 0755)
+	}
+	os.WriteFile(filepath.Join(instanceDir, "config.yml"), []byte{}, 0644)
+
+	if !IsInstanceDirectory(instanceDir) || IsInstanceDirectory(filepath.Join(instanceDir, "spaces")) {
+		t.Errorf("Expected only %s to be an instance directory", instanceDir)
+	}
+
+	dirs, err := GetSpaceDirectories(instanceDir, "DOC*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprint([]string{filepath.Join(instanceDir, "spaces", "DOC"), filepath.Join(instanceDir, "spaces", "DOCS")})
+	if fmt.Sprint(dirs) != expected {
+		t.Errorf("Expected %s, got %v", expected, dirs)
+	}
+
+	if dirs, _ := GetSpaceDirectories(instanceDir, ""); len(dirs) != 3 {
+		t.Errorf("Expected all 3 spaces without pattern, got %v", dirs)
+	}
+}
@@ -5,46 +5,102 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"io"
+	"strings"
 )
 
+const (
+	// values encrypted with AES-GCM, authenticated so a wrong secret or modified value is detected
+	GCM_PREFIX = "AES_GCM_V2:"
+	// values encrypted with AES-CFB by earlier versions, still supported for reading
+	LEGACY_PREFIX = "AES_ENC:"
+)
+
+var ErrDecrypt = errors.New("wrong secret or the value was modified")
+
 func encode(b []byte) string {
 	return base64.StdEncoding.EncodeToString(b)
 }
-func decode(s string) []byte {
-	data, err := base64.StdEncoding.DecodeString(s)
+func decode(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(s)
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, GCM_PREFIX) || strings.HasPrefix(value, LEGACY_PREFIX)
+}
+
+// Encrypt returns the text encrypted with AES-GCM, prefixed with GCM_PREFIX
+func Encrypt(text, secret string) (string, error) {
+	gcm, err := newGCM(secret)
 	if err != nil {
-		panic(err)
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
 	}
-	return data
+
+	return GCM_PREFIX + encode(gcm.Seal(nonce, nonce, []byte(text), nil)), nil
 }
 
-func Encrypt(text, secret string) (string, error) {
+// Decrypt accepts values produced by Encrypt as well as legacy AES_ENC: values
+func Decrypt(value, secret string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, GCM_PREFIX):
+		return decryptGCM(strings.TrimPrefix(value, GCM_PREFIX), secret)
+	case strings.HasPrefix(value, LEGACY_PREFIX):
+		return decryptCFB(strings.TrimPrefix(value, LEGACY_PREFIX), secret)
+	default:
+		return "", errors.New("value is not encrypted")
+	}
+}
+
+func newGCM(secret string) (cipher.AEAD, error) {
 	block, err := aes.NewCipher([]byte(secret))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	plainText := []byte(text)
-	cipherText := make([]byte, aes.BlockSize+len(plainText))
-	iv := cipherText[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+	return cipher.NewGCM(block)
+}
+
+func decryptGCM(text, secret string) (string, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
 		return "", err
 	}
-	cfb := cipher.NewCFBEncrypter(block, iv)
-	cfb.XORKeyStream(cipherText[aes.BlockSize:], plainText)
-	return encode(cipherText), nil
+
+	cipherText, err := decode(text)
+	if err != nil || len(cipherText) < gcm.NonceSize() {
+		return "", ErrDecrypt
+	}
+
+	nonce := cipherText[:gcm.NonceSize()]
+	plainText, err := gcm.Open(nil, nonce, cipherText[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+
+	return string(plainText), nil
 }
 
-func Decrypt(text, secret string) (string, error) {
+// decryptCFB has no way to detect a wrong secret, rotate-secret migrates these values to AES-GCM
+func decryptCFB(text, secret string) (string, error) {
 	block, err := aes.NewCipher([]byte(secret))
 	if err != nil {
 		return "", err
 	}
-	cipherText := decode(text)
+
+	cipherText, err := decode(text)
+	if err != nil || len(cipherText) < aes.BlockSize {
+		return "", ErrDecrypt
+	}
+
 	iv := cipherText[:aes.BlockSize]
 	cfb := cipher.NewCFBDecrypter(block, iv)
-	plainText := make([]byte, len(cipherText))
+	plainText := make([]byte, len(cipherText)-aes.BlockSize)
 	cfb.XORKeyStream(plainText, cipherText[aes.BlockSize:])
 	return string(plainText), nil
 }
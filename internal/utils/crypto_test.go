@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSecret = "abcdefghijklmnopqrstuvwx"
+
+func TestEncryptDecrypt(t *testing.T) {
+	encrypted, err := Encrypt("my-token", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encrypted, GCM_PREFIX) {
+		t.Fatalf("Expected %s prefix, got %s", GCM_PREFIX, encrypted)
+	}
+
+	decrypted, err := Decrypt(encrypted, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted != "my-token" {
+		t.Errorf("Expected 'my-token', got '%s'", decrypted)
+	}
+}
+
+func TestDecryptWrongSecret(t *testing.T) {
+	encrypted, _ := Encrypt("my-token", testSecret)
+
+	if _, err := Decrypt(encrypted, "xwvutsrqponmlkjihgfedcba"); err != ErrDecrypt {
+		t.Errorf("Expected ErrDecrypt for a wrong secret, got %v", err)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	encrypted, _ := Encrypt("my-token", testSecret)
+	data, _ := decode(strings.TrimPrefix(encrypted, GCM_PREFIX))
+	data[len(data)-1] ^= 1
+
+	if _, err := Decrypt(GCM_PREFIX+encode(data), testSecret); err != ErrDecrypt {
+		t.Errorf("Expected ErrDecrypt for a modified value, got %v", err)
+	}
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	// encrypted with AES-CFB by earlier versions
+	decrypted, err := Decrypt("AES_ENC:PnxiKQC5AxaStZ28/6OX6L7Nar+z", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted != "token" {
+		t.Errorf("Expected 'token', got '%q'", decrypted)
+	}
+}
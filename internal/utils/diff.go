@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+type DiffOp byte
+
+const (
+	EQUAL  DiffOp = ' '
+	INSERT DiffOp = '+'
+	DELETE DiffOp = '-'
+)
+
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// DiffLines computes a line based diff turning a into b, using the longest common subsequence of both
+func DiffLines(a, b []string) []DiffLine {
+	// lcs[i][j] holds the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []DiffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{EQUAL, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{DELETE, a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{INSERT, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{DELETE, a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{INSERT, b[j]})
+	}
+
+	return lines
+}
+
+// UnifiedDiff returns the differences between a and b in unified diff format, with the given number
+// of context lines around each change. An empty string is returned when both are equal.
+func UnifiedDiff(fromName, toName, a, b string, context int) string {
+	lines := DiffLines(splitLines(a), splitLines(b))
+
+	hunks := []string{}
+	for start := 0; start < len(lines); {
+		if lines[start].Op == EQUAL {
+			start++
+			continue
+		}
+
+		// extend the hunk until there are more than 2*context unchanged lines in a row
+		end := start
+		for k := start; k < len(lines); k++ {
+			if lines[k].Op != EQUAL {
+				end = k
+			} else if k-end > 2*context {
+				break
+			}
+		}
+
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := end + context + 1
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		hunks = append(hunks, formatHunk(lines, from, to))
+		start = to
+	}
+
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("--- %s\n+++ %s\n%s", fromName, toName, strings.Join(hunks, ""))
+}
+
+func formatHunk(lines []DiffLine, from, to int) string {
+	// line numbers of the hunk start in a and b (1 based)
+	aStart, bStart := 1, 1
+	for _, l := range lines[:from] {
+		if l.Op != INSERT {
+			aStart++
+		}
+		if l.Op != DELETE {
+			bStart++
+		}
+	}
+
+	aCount, bCount := 0, 0
+	body := strings.Builder{}
+	for _, l := range lines[from:to] {
+		if l.Op != INSERT {
+			aCount++
+		}
+		if l.Op != DELETE {
+			bCount++
+		}
+		body.WriteString(fmt.Sprintf("%c%s\n", l.Op, l.Text))
+	}
+
+	if aCount == 0 {
+		aStart--
+	}
+	if bCount == 0 {
+		bStart--
+	}
+
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n%s", aStart, aCount, bStart, bCount, body.String())
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
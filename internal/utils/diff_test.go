@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten"
+	b := "one\ntwo\nthree\n4\nfive\nsix\nseven\neight\nnine\nten\neleven"
+
+	expected := `--- remote
++++ local
+@@ -2,5 +2,5 @@
+ two
+ three
+-four
++4
+ five
+ six
+@@ -9,2 +9,3 @@
+ nine
+ ten
++eleven
+`
+	actual := UnifiedDiff("remote", "local", a, b, 2)
+	if actual != expected {
+		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
+
+func TestUnifiedDiffEqual(t *testing.T) {
+	if diff := UnifiedDiff("remote", "local", "same\ntext", "same\ntext", 3); diff != "" {
+		t.Fatalf("Expected no diff for equal text, got:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiffEmpty(t *testing.T) {
+	expected := "--- remote\n+++ local\n@@ -0,0 +1,2 @@\n+new\n+page\n"
+	if actual := UnifiedDiff("remote", "local", "", "new\npage", 3); actual != expected {
+		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DEFAULT_REGISTRY_FILENAME = "instances.yml"
+
+// the registry keeps track of instance directories created with `y2c instances new`
+type instanceRegistry struct {
+	Instances []string `yaml:"instances"`
+}
+
+func GetDefaultRegistryPath() string {
+	return filepath.Join(GetDefaultY2cHomeDir(), DEFAULT_REGISTRY_FILENAME)
+}
+
+func GetRegisteredInstances() []string {
+	return readRegistry().Instances
+}
+
+func RegisterInstance(instanceDir string) {
+	registry := readRegistry()
+	for _, dir := range registry.Instances {
+		if dir == instanceDir {
+			return
+		}
+	}
+	registry.Instances = append(registry.Instances, instanceDir)
+
+	data, err := yaml.Marshal(&registry)
+	if err != nil {
+		panic(err)
+	}
+
+	os.MkdirAll(GetDefaultY2cHomeDir(), 0700)
+	if err := os.WriteFile(GetDefaultRegistryPath(), data, 0644); err != nil {
+		panic(err)
+	}
+}
+
+func readRegistry() instanceRegistry {
+	registry := instanceRegistry{}
+
+	data, err := os.ReadFile(GetDefaultRegistryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return registry
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		panic(err)
+	}
+
+	return registry
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GetYamlNode returns the scalar value at path in a mapping document
+func GetYamlNode(node *yaml.Node, path []string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return GetYamlNode(node.Content[0], path)
+	}
+	if len(path) == 0 {
+		return node
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == path[0] {
+			return GetYamlNode(node.Content[i+1], path[1:])
+		}
+	}
+
+	return nil
+}
+
+func MarshalYamlNode(node *yaml.Node) []byte {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		panic(err)
+	}
+
+	return buf.Bytes()
+}